@@ -0,0 +1,87 @@
+package sensitivewordfilter
+
+import (
+	"sort"
+
+	"github.com/hellobchain/sensitivewordfilter/filter"
+	"github.com/hellobchain/sensitivewordfilter/store"
+)
+
+// changeSet 合并后的增量变更
+type changeSet struct {
+	added   map[string]store.Entry
+	removed map[string]bool
+	version uint64
+}
+
+// readChanges 读取并合并存储中版本号 since 之后的变更
+// 存储不提供变更记录(store.ChangeLog)、读取失败或变更记录已截断时返回false
+func readChanges(s store.SensitivewordStore, since uint64) (*changeSet, bool) {
+	cl, ok := s.(store.ChangeLog)
+	if !ok {
+		return nil, false
+	}
+	changes, err := cl.Changes(since)
+	if err != nil {
+		return nil, false
+	}
+	cs := &changeSet{
+		added:   make(map[string]store.Entry),
+		removed: make(map[string]bool),
+		version: since,
+	}
+	for _, c := range changes {
+		for _, entry := range c.Added {
+			cs.added[entry.Word] = entry
+			delete(cs.removed, entry.Word)
+		}
+		for _, word := range c.Removed {
+			cs.removed[word] = true
+			delete(cs.added, word)
+		}
+		cs.version = c.Version
+	}
+	return cs, true
+}
+
+// apply 将变更增量应用到过滤器，过滤器不支持增量更新(filter.Updater)时返回false
+func (cs *changeSet) apply(ft filter.SensitivewordFilter) (filter.SensitivewordFilter, bool) {
+	up, ok := ft.(filter.Updater)
+	if !ok {
+		return nil, false
+	}
+	added := make([]string, 0, len(cs.added))
+	for word := range cs.added {
+		added = append(added, word)
+	}
+	removed := make([]string, 0, len(cs.removed))
+	for word := range cs.removed {
+		removed = append(removed, word)
+	}
+	// 保证相同的变更得到相同的过滤器
+	sort.Strings(added)
+	sort.Strings(removed)
+	return up.Update(added, removed), true
+}
+
+// applyMetas 返回应用变更后的词条附加信息，不修改 metas
+func (cs *changeSet) applyMetas(metas map[string]filter.Meta) map[string]filter.Meta {
+	result := make(map[string]filter.Meta, len(metas))
+	for word, meta := range metas {
+		if !cs.removed[word] {
+			result[word] = meta
+		}
+	}
+	for word, entry := range cs.added {
+		if entry.Category == "" && entry.Severity == 0 && entry.Action == "" {
+			delete(result, word)
+			continue
+		}
+		result[word] = filter.Meta{
+			Category: entry.Category,
+			Severity: entry.Severity,
+			Action:   entry.Action,
+		}
+	}
+	return result
+}
@@ -0,0 +1,149 @@
+package sensitivewordfilter
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/hellobchain/sensitivewordfilter/filter"
+	"github.com/hellobchain/sensitivewordfilter/store"
+)
+
+// Verdict 审核结论
+type Verdict string
+
+const (
+	// VerdictAllow 允许
+	VerdictAllow Verdict = "allow"
+	// VerdictReview 转人工审核
+	VerdictReview Verdict = "review"
+	// VerdictBlock 拒绝
+	VerdictBlock Verdict = "block"
+)
+
+// Policy 审核策略
+// 每个命中的得分为 严重程度 × 分类权重，文本得分为所有命中得分之和，
+// 得分达到 BlockThreshold 时拒绝，达到 ReviewThreshold 时转人工审核，阈值为0时不启用。
+// 词条的处理方式为 block 或 review 时，命中即至少得出对应的结论
+type Policy struct {
+	// CategoryWeights 分类权重，未配置的分类使用 DefaultWeight
+	CategoryWeights map[string]float64 `json:"categoryWeights,omitempty"`
+	// DefaultWeight 默认分类权重，为0时使用1
+	DefaultWeight float64 `json:"defaultWeight,omitempty"`
+	// DefaultSeverity 未设置严重程度的词条使用的严重程度，为0时使用1
+	DefaultSeverity int `json:"defaultSeverity,omitempty"`
+	// ReviewThreshold 转人工审核的得分阈值
+	ReviewThreshold float64 `json:"reviewThreshold,omitempty"`
+	// BlockThreshold 拒绝的得分阈值
+	BlockThreshold float64 `json:"blockThreshold,omitempty"`
+}
+
+// DefaultPolicy 默认审核策略，任意命中转人工审核，得分达到 store.MaxSeverity 时拒绝
+func DefaultPolicy() *Policy {
+	return &Policy{
+		ReviewThreshold: 1,
+		BlockThreshold:  store.MaxSeverity,
+	}
+}
+
+// LoadPolicy 从JSON数据中读取审核策略
+func LoadPolicy(r io.Reader) (*Policy, error) {
+	p := new(Policy)
+	if err := json.NewDecoder(r).Decode(p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// LoadPolicyFile 从JSON文件中读取审核策略
+func LoadPolicyFile(path string) (*Policy, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadPolicy(f)
+}
+
+// Decision 审核结果
+type Decision struct {
+	// Verdict 审核结论
+	Verdict Verdict
+	// Score 文本得分
+	Score float64
+	// Matches 参与计分的命中
+	Matches []filter.Match
+	// Err 查找敏感词失败时的错误，此时结论为 VerdictReview
+	Err error
+}
+
+// Score 计算单个命中的得分
+func (p *Policy) Score(m filter.Match) float64 {
+	severity := m.Meta.Severity
+	if severity == 0 {
+		severity = p.DefaultSeverity
+	}
+	if severity == 0 {
+		severity = 1
+	}
+	weight, ok := p.CategoryWeights[m.Meta.Category]
+	if !ok {
+		weight = p.DefaultWeight
+		if weight == 0 {
+			weight = 1
+		}
+	}
+	return float64(severity) * weight
+}
+
+// Decide 使用过滤器查找文本中的敏感词并得出审核结论
+// 过滤器不支持位置信息(filter.Matcher)时，命中不携带附加信息
+func (p *Policy) Decide(f filter.SensitivewordFilter, text string) Decision {
+	matches, err := findMatches(f, text)
+	if err != nil {
+		return Decision{Verdict: VerdictReview, Err: err}
+	}
+	d := Decision{Verdict: VerdictAllow}
+	// 同一词条相互重叠的命中(如拼音的多种写法)只计分一次
+	ends := make(map[string]int)
+	for _, m := range matches {
+		if end, ok := ends[m.Entry]; ok && m.Start < end {
+			continue
+		}
+		ends[m.Entry] = m.End
+		d.Score += p.Score(m)
+		d.Matches = append(d.Matches, m)
+		switch m.Meta.Action {
+		case store.ActionBlock:
+			d.Verdict = VerdictBlock
+		case store.ActionReview:
+			if d.Verdict == VerdictAllow {
+				d.Verdict = VerdictReview
+			}
+		}
+	}
+	switch {
+	case p.BlockThreshold > 0 && d.Score >= p.BlockThreshold:
+		d.Verdict = VerdictBlock
+	case p.ReviewThreshold > 0 && d.Score >= p.ReviewThreshold && d.Verdict == VerdictAllow:
+		d.Verdict = VerdictReview
+	}
+	return d
+}
+
+func findMatches(f filter.SensitivewordFilter, text string) ([]filter.Match, error) {
+	if m, ok := f.(filter.Matcher); ok {
+		return m.FindMatches(text)
+	}
+	data, err := f.FilterResult(text)
+	if err != nil {
+		return nil, err
+	}
+	var matches []filter.Match
+	for word, count := range data {
+		for i := 0; i < count; i++ {
+			matches = append(matches, filter.Match{Word: word, Entry: word})
+		}
+	}
+	return matches, nil
+}
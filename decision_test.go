@@ -0,0 +1,99 @@
+package sensitivewordfilter
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hellobchain/sensitivewordfilter/filter/newdfa"
+	"github.com/hellobchain/sensitivewordfilter/store"
+	"github.com/hellobchain/sensitivewordfilter/store/memory"
+)
+
+func TestDecide(t *testing.T) {
+	words, err := memory.NewMemoryStore(memory.MemoryConfig{
+		DataSource: []string{"广告"},
+		Entries: []store.Entry{
+			{Word: "暴力", Category: "abuse", Severity: 2},
+			{Word: "赌博", Category: "gamble", Severity: 1, Action: store.ActionBlock},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager := NewSensitivewordManager(words, nil, newdfa.NewNodeChanFilter(words.Read()), time.Millisecond*10)
+	defer manager.Close()
+	policy, err := LoadPolicy(strings.NewReader(`{"categoryWeights":{"abuse":1.5},"reviewThreshold":2,"blockThreshold":6}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager.SetPolicy(policy)
+
+	cases := []struct {
+		text    string
+		verdict Verdict
+		score   float64
+		matches int
+	}{
+		{"你好", VerdictAllow, 0, 0},
+		{"广告", VerdictAllow, 1, 1},
+		{"暴力", VerdictReview, 3, 1},
+		{"暴力暴力广告", VerdictBlock, 7, 3},
+		{"赌博", VerdictBlock, 1, 1},
+	}
+	for _, c := range cases {
+		d := manager.Decide(c.text)
+		if d.Err != nil {
+			t.Fatal(d.Err)
+		}
+		if d.Verdict != c.verdict || d.Score != c.score || len(d.Matches) != c.matches {
+			t.Errorf("decide %s got %s %v %d matches, expect %s %v %d", c.text, d.Verdict, d.Score, len(d.Matches), c.verdict, c.score, c.matches)
+		}
+	}
+
+	reviewAll := &Policy{ReviewThreshold: 1}
+	if err := manager.SetPolicyLoader(func() (*Policy, error) { return reviewAll, nil }); err != nil {
+		t.Fatal(err)
+	}
+	if d := manager.Decide("广告"); d.Verdict != VerdictReview {
+		t.Errorf("expect policy loaded, got %s", d.Verdict)
+	}
+	reviewAll = &Policy{}
+	if err := words.Write("色情"); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for manager.Decide("广告").Verdict != VerdictAllow {
+		if time.Now().After(deadline) {
+			t.Fatalf("policy not reloaded with dictionary")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func TestDecideNilPolicy(t *testing.T) {
+	words, err := memory.NewMemoryStore(memory.MemoryConfig{DataSource: []string{"广告"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager := NewSensitivewordManager(words, nil, newdfa.NewNodeChanFilter(words.Read()), time.Millisecond*10)
+	defer manager.Close()
+
+	manager.SetPolicy(nil)
+	if d := manager.Decide("广告"); d.Err != nil || d.Verdict != DefaultPolicy().Decide(manager.Filter(), "广告").Verdict {
+		t.Errorf("expect default policy after set nil, got %+v", d)
+	}
+	if err := manager.SetPolicyLoader(func() (*Policy, error) { return nil, nil }); err != nil {
+		t.Fatal(err)
+	}
+	if manager.Policy() == nil {
+		t.Errorf("expect default policy when loader returns nil")
+	}
+	if err := manager.Reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if manager.Policy() == nil {
+		t.Errorf("expect default policy after reload with nil policy")
+	}
+}
@@ -0,0 +1,486 @@
+package ac
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/hellobchain/sensitivewordfilter/filter"
+)
+
+// NewNodeReaderFilter 创建AC自动机过滤器，实现敏感词的过滤
+// 从可读流中读取敏感词数据(以指定的分隔符读取数据)
+func NewNodeReaderFilter(rd io.Reader, delim byte) filter.SensitivewordFilter {
+	nf := newNodeFilter()
+	buf := new(bytes.Buffer)
+	_, _ = io.Copy(buf, rd)
+	buf.WriteByte(delim)
+	for {
+		line, err := buf.ReadString(delim)
+		if err != nil {
+			break
+		}
+		if line == "" {
+			continue
+		}
+		nf.addSensitivewords(line)
+	}
+	buf.Reset()
+	nf.build()
+	return nf
+}
+
+// NewNodeChanFilter 创建AC自动机过滤器，实现敏感词的过滤
+// 从通道中读取敏感词数据
+func NewNodeChanFilter(text <-chan string) filter.SensitivewordFilter {
+	nf := newNodeFilter()
+	for v := range text {
+		nf.addSensitivewords(v)
+	}
+	nf.build()
+	return nf
+}
+
+// NewNodeFilter 创建AC自动机过滤器，实现敏感词的过滤
+// 从切片中读取敏感词数据
+func NewNodeFilter(text []string) filter.SensitivewordFilter {
+	nf := newNodeFilter()
+	for i, l := 0, len(text); i < l; i++ {
+		nf.addSensitivewords(text[i])
+	}
+	nf.build()
+	return nf
+}
+
+func newNode(depth int) *node {
+	return &node{
+		depth: depth,
+		child: make(map[rune]*node),
+	}
+}
+
+// node AC自动机上的一个状态
+type node struct {
+	end   bool
+	word  string
+	depth int
+	child map[rune]*node
+	// fail 失败链接：当前路径的最长真后缀所在的状态
+	fail *node
+	// output 输出链接：沿失败链接可达的最近一个词尾状态
+	output *node
+}
+
+// NodeFilter 基于Aho-Corasick自动机的敏感词过滤器，
+// 一次扫描即可找出文本中出现的全部敏感词
+type NodeFilter struct {
+	root  *node
+	noise *regexp.Regexp
+	// mux 保护查找与增删敏感词的并发访问
+	mux sync.RWMutex
+}
+
+func newNodeFilter() *NodeFilter {
+	return &NodeFilter{
+		root:  newNode(0),
+		noise: regexp.MustCompile(`[\|\s&%$@*]+`),
+	}
+}
+
+func (nf *NodeFilter) addSensitivewords(text string) {
+	uchars := []rune(strings.TrimSpace(text))
+	if len(uchars) == 0 {
+		return
+	}
+	n := nf.root
+	for i, l := 0, len(uchars); i < l; i++ {
+		next, ok := n.child[uchars[i]]
+		if !ok {
+			next = newNode(n.depth + 1)
+			n.child[uchars[i]] = next
+		}
+		n = next
+	}
+	n.end = true
+	n.word = string(uchars)
+}
+
+func (nf *NodeFilter) delSensitivewords(text string) {
+	uchars := []rune(strings.TrimSpace(text))
+	if len(uchars) == 0 {
+		return
+	}
+	nodes := []*node{nf.root}
+	for _, r := range uchars {
+		next, ok := nodes[len(nodes)-1].child[r]
+		if !ok {
+			return
+		}
+		nodes = append(nodes, next)
+	}
+	nodes[len(nodes)-1].end = false
+	nodes[len(nodes)-1].word = ""
+	// 自词尾向上删除不再是词尾且没有子节点的节点，失败链接由之后的 build 重新计算
+	for i := len(uchars) - 1; i >= 0; i-- {
+		if n := nodes[i+1]; n.end || len(n.child) > 0 {
+			return
+		}
+		delete(nodes[i].child, uchars[i])
+	}
+}
+
+// build 按广度优先顺序重新计算失败链接和输出链接
+func (nf *NodeFilter) build() {
+	nf.root.fail = nil
+	nf.root.output = nil
+	queue := make([]*node, 0, len(nf.root.child))
+	for _, n := range nf.root.child {
+		n.fail = nf.root
+		n.output = nil
+		queue = append(queue, n)
+	}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for r, n := range current.child {
+			fail := current.fail
+			for fail != nil {
+				if next, ok := fail.child[r]; ok {
+					fail = next
+					break
+				}
+				fail = fail.fail
+			}
+			if fail == nil {
+				fail = nf.root
+			}
+			n.fail = fail
+			if fail.end {
+				n.output = fail
+			} else {
+				n.output = fail.output
+			}
+			queue = append(queue, n)
+		}
+	}
+}
+
+// next 从状态 n 读入字符 r 后转移到的状态
+func (nf *NodeFilter) next(n *node, r rune) *node {
+	for {
+		if next, ok := n.child[r]; ok {
+			return next
+		}
+		if n == nf.root {
+			return n
+		}
+		n = n.fail
+	}
+}
+
+// scan 对文本进行一次扫描，每找到一个敏感词即以其字符区间 [start, end) 及词尾状态回调 fn，
+// fn 返回false时停止扫描
+func (nf *NodeFilter) scan(uchars []rune, fn func(start, end int, n *node) bool) {
+	nf.mux.RLock()
+	defer nf.mux.RUnlock()
+	n := nf.root
+	for i, l := 0, len(uchars); i < l; i++ {
+		n = nf.next(n, uchars[i])
+		out := n
+		if !out.end {
+			out = out.output
+		}
+		for ; out != nil; out = out.output {
+			if !fn(i+1-out.depth, i+1, out) {
+				return
+			}
+		}
+	}
+}
+
+// MaxSpan 实现filter.Spanner接口，返回最长的敏感词的字符个数
+func (nf *NodeFilter) MaxSpan() int {
+	nf.mux.RLock()
+	defer nf.mux.RUnlock()
+	span := 0
+	var walk func(n *node)
+	walk = func(n *node) {
+		if n.end && n.depth > span {
+			span = n.depth
+		}
+		for _, next := range n.child {
+			walk(next)
+		}
+	}
+	walk(nf.root)
+	return span
+}
+
+// Compact 实现filter.Compactor接口，重建节点并释放删除敏感词后map中残留的空间
+func (nf *NodeFilter) Compact() {
+	nf.mux.Lock()
+	defer nf.mux.Unlock()
+	if nf.root = compactNode(nf.root); nf.root == nil {
+		nf.root = newNode(0)
+	}
+	nf.build()
+}
+
+// compactNode 复制以 n 为根的子树(不含失败链接及输出链接)，不再通向词尾的子树返回nil
+func compactNode(n *node) *node {
+	c := &node{
+		end:   n.end,
+		word:  n.word,
+		depth: n.depth,
+		child: make(map[rune]*node, len(n.child)),
+	}
+	for r, next := range n.child {
+		if next = compactNode(next); next != nil {
+			c.child[r] = next
+		}
+	}
+	if !c.end && len(c.child) == 0 {
+		return nil
+	}
+	return c
+}
+
+// Stats 实现filter.Compactor接口，获取自动机的统计信息
+func (nf *NodeFilter) Stats() filter.Stats {
+	nf.mux.RLock()
+	defer nf.mux.RUnlock()
+	var stats filter.Stats
+	var walk func(n *node)
+	walk = func(n *node) {
+		stats.Nodes++
+		stats.Bytes += int64(unsafe.Sizeof(*n)) + filter.MapBytes(len(n.child), unsafe.Sizeof(rune(0))+unsafe.Sizeof(n))
+		if n.end {
+			stats.Words++
+			stats.Bytes += int64(len(n.word))
+			if n.depth > stats.Depth {
+				stats.Depth = n.depth
+			}
+		}
+		for _, next := range n.child {
+			walk(next)
+		}
+	}
+	walk(nf.root)
+	return stats
+}
+
+// Add 增加敏感词，可与查找并发调用
+func (nf *NodeFilter) Add(text ...string) {
+	nf.mux.Lock()
+	defer nf.mux.Unlock()
+	for _, v := range text {
+		nf.addSensitivewords(v)
+	}
+	nf.build()
+}
+
+// Remove 移除敏感词，可与查找并发调用
+func (nf *NodeFilter) Remove(text ...string) {
+	nf.mux.Lock()
+	defer nf.mux.Unlock()
+	for _, v := range text {
+		nf.delSensitivewords(v)
+	}
+	nf.build()
+}
+
+func (nf *NodeFilter) Filter(text string, excludes ...rune) ([]string, error) {
+	buf := bytes.NewBufferString(text)
+	defer buf.Reset()
+	return nf.FilterReader(buf, excludes...)
+}
+
+func (nf *NodeFilter) FilterResult(text string, excludes ...rune) (map[string]int, error) {
+	buf := bytes.NewBufferString(text)
+	defer buf.Reset()
+	return nf.FilterReaderResult(buf, excludes...)
+}
+
+func (nf *NodeFilter) FilterReader(reader io.Reader, excludes ...rune) ([]string, error) {
+	data, err := nf.FilterReaderResult(reader, excludes...)
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	for k := range data {
+		result = append(result, k)
+	}
+	return result, nil
+}
+
+func (nf *NodeFilter) FilterReaderResult(reader io.Reader, excludes ...rune) (map[string]int, error) {
+	var (
+		uchars []rune
+	)
+	data := make(map[string]int)
+	bi := bufio.NewReader(reader)
+	for {
+		ur, _, err := bi.ReadRune()
+		if err != nil {
+			if err != io.EOF {
+				return nil, err
+			}
+			break
+		}
+		if nf.checkExclude(ur, excludes...) {
+			continue
+		}
+		if nf.IsSeparator(ur) && len(uchars) > 0 {
+			nf.doFilter(uchars, data)
+			uchars = nil
+			continue
+		}
+		uchars = append(uchars, ur)
+	}
+	if len(uchars) > 0 {
+		nf.doFilter(uchars, data)
+	}
+	return data, nil
+}
+
+func (nf *NodeFilter) doFilter(uchars []rune, data map[string]int) {
+	nf.scan(uchars, func(start, end int, _ *node) bool {
+		data[string(uchars[start:end])]++
+		return true
+	})
+}
+
+func (nf *NodeFilter) Replace(text string, delim rune, excludes ...rune) (string, error) {
+	uchars := []rune(text)
+	var (
+		kept  []rune
+		index []int
+	)
+	for i, l := 0, len(uchars); i < l; i++ {
+		if nf.checkExclude(uchars[i], excludes...) {
+			continue
+		}
+		kept = append(kept, uchars[i])
+		index = append(index, i)
+	}
+	nf.scan(kept, func(start, end int, _ *node) bool {
+		for i := index[start]; i <= index[end-1]; i++ {
+			uchars[i] = delim
+		}
+		return true
+	})
+	return string(uchars), nil
+}
+
+// FindMatches 查找文本中出现的所有敏感词及其在原文中的位置
+func (nf *NodeFilter) FindMatches(text string, opts ...filter.MatchOption) ([]filter.Match, error) {
+	o := filter.NewMatchOptions(opts...)
+	t := filter.NewText(text, o, nf.noise, nil)
+	var matches []filter.Match
+	nf.scan(t.Runes, func(start, end int, n *node) bool {
+		m := t.Match(start, end)
+		m.Entry = n.word
+		matches = append(matches, m)
+		return true
+	})
+	filter.SortMatches(matches)
+	return filter.ResolveOverlaps(matches, o.Overlap), nil
+}
+
+func (nf *NodeFilter) IsExist(text string, excludes ...rune) bool {
+	buf := bytes.NewBufferString(text)
+	defer buf.Reset()
+	return nf.IsExistReader(buf, excludes...)
+}
+
+func (nf *NodeFilter) IsExistReader(reader io.Reader, excludes ...rune) bool {
+	var (
+		uchars []rune
+	)
+	bi := bufio.NewReader(reader)
+	for {
+		ur, _, err := bi.ReadRune()
+		if err != nil {
+			if err != io.EOF {
+				return false
+			}
+			break
+		}
+		if nf.checkExclude(ur, excludes...) {
+			continue
+		}
+		if nf.IsSeparator(ur) && len(uchars) > 0 {
+			if validated, _ := nf.validate(uchars); !validated {
+				return true
+			}
+			uchars = nil
+			continue
+		}
+		uchars = append(uchars, ur)
+	}
+	if len(uchars) > 0 {
+		validated, _ := nf.validate(uchars)
+		return !validated
+	}
+	return false
+}
+
+// FindIn 检测敏感词
+func (nf *NodeFilter) FindIn(text string, excludes ...rune) (bool, string) {
+	validated, first := nf.Validate(text, excludes...)
+	return !validated, first
+}
+
+// Validate 检测字符串是否合法，如不合法则返回false和检测到的第一个敏感词
+func (nf *NodeFilter) Validate(text string, excludes ...rune) (bool, string) {
+	var newWchar []rune
+	uchars := []rune(text)
+	for i, l := 0, len(uchars); i < l; i++ {
+		if nf.checkExclude(uchars[i], excludes...) {
+			continue
+		}
+		newWchar = append(newWchar, uchars[i])
+	}
+	return nf.validate(newWchar)
+}
+
+func (nf *NodeFilter) validate(uchars []rune) (bool, string) {
+	var (
+		validated = true
+		first     string
+	)
+	nf.scan(uchars, func(start, end int, _ *node) bool {
+		validated = false
+		first = string(uchars[start:end])
+		return false
+	})
+	return validated, first
+}
+
+// IsSeparator 实现filter.Segmenter接口，是否为切分文本的空白或标点
+func (nf *NodeFilter) IsSeparator(r rune) bool {
+	return filter.IsSeparator(nil, r)
+}
+
+func (nf *NodeFilter) checkExclude(u rune, excludes ...rune) bool {
+	for i, l := 0, len(excludes); i < l; i++ {
+		if u == excludes[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateNoisePattern 更新去噪模式
+func (nf *NodeFilter) UpdateNoisePattern(pattern string) {
+	nf.noise = regexp.MustCompile(pattern)
+}
+
+// RemoveNoise 去除空格等噪音
+func (nf *NodeFilter) RemoveNoise(text string) string {
+	return nf.noise.ReplaceAllString(text, "")
+}
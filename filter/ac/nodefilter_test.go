@@ -0,0 +1,140 @@
+package ac
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/hellobchain/sensitivewordfilter/filter"
+	"github.com/hellobchain/sensitivewordfilter/filter/filtertest"
+)
+
+func newTestFilter() *NodeFilter {
+	return NewNodeFilter([]string{"有一个东西", "一个东西", "一个", "东西", "个东"}).(*NodeFilter)
+}
+
+func TestFilterResult(t *testing.T) {
+	nf := newTestFilter()
+
+	testcases := []struct {
+		Text   string
+		Expect map[string]int
+	}{
+		{"我有一个东东西", map[string]int{"一个": 1, "个东": 1, "东西": 1}},
+		{"我有一个东西", map[string]int{"有一个东西": 1, "一个东西": 1, "一个": 1, "个东": 1, "东西": 1}},
+		{"一个东西，一个", map[string]int{"一个东西": 1, "一个": 2, "个东": 1, "东西": 1}},
+		{"一个物体", map[string]int{"一个": 1}},
+		{"两样物体", map[string]int{}},
+	}
+
+	for _, tc := range testcases {
+		got, err := nf.FilterResult(tc.Text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(tc.Expect, got) {
+			t.Errorf("filter result %s, got %v, expect %v", tc.Text, got, tc.Expect)
+		}
+	}
+}
+
+func TestFilterExcludes(t *testing.T) {
+	nf := newTestFilter()
+
+	got, err := nf.Filter("一*个 东|西", '*', '|')
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(got)
+	expect := []string{"一个", "东西"}
+	if !reflect.DeepEqual(expect, got) {
+		t.Errorf("filter with excludes, got %v, expect %v", got, expect)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	nf := newTestFilter()
+
+	testcases := []struct {
+		Text     string
+		Excludes []rune
+		Expect   string
+	}{
+		{"我有一个东东西", nil, "我有*****"},
+		{"两个东西", nil, "两***"},
+		{"一@个物体", []rune{'@'}, "***物体"},
+		{"两样物体", nil, "两样物体"},
+	}
+
+	for _, tc := range testcases {
+		got, err := nf.Replace(tc.Text, '*', tc.Excludes...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.Expect {
+			t.Errorf("replace %s, got %s, expect %s", tc.Text, got, tc.Expect)
+		}
+	}
+}
+
+func TestAddRemove(t *testing.T) {
+	nf := newTestFilter()
+
+	if !nf.IsExist("两个东西") {
+		t.Errorf("expect sensitive word in 两个东西")
+	}
+	nf.Remove("个东", "东西")
+	if nf.IsExist("两个东西") {
+		t.Errorf("expect no sensitive word in 两个东西 after remove")
+	}
+	nf.Add("两个")
+	if pass, first := nf.Validate("两个东西"); pass || first != "两个" {
+		t.Errorf("validate after add, got %v, %s, expect false, 两个", pass, first)
+	}
+}
+
+func TestRemovePrune(t *testing.T) {
+	nf := NewNodeFilter([]string{"暴力", "暴力倾向", "力倾"})
+	before := nf.(filter.Compactor).Stats()
+	if before.Nodes != 7 || before.Words != 3 || before.Depth != 4 {
+		t.Fatalf("unexpected stats %+v", before)
+	}
+	nf.Remove("暴力倾向", "力倾")
+	stats := nf.(filter.Compactor).Stats()
+	if stats.Nodes != 3 || stats.Words != 1 || stats.Depth != 2 {
+		t.Errorf("expect removed nodes pruned, got %+v", stats)
+	}
+	// 删除节点后失败链接不应再指向已删除的状态
+	if got, _ := nf.FilterResult("暴力倾向"); !reflect.DeepEqual(got, map[string]int{"暴力": 1}) {
+		t.Errorf("filter after remove got %v", got)
+	}
+
+	nf.Remove("暴力")
+	nf.(filter.Compactor).Compact()
+	if stats := nf.(filter.Compactor).Stats(); stats.Nodes != 1 || stats.Words != 0 {
+		t.Errorf("expect only root left, got %+v", stats)
+	}
+	nf.Add("力倾")
+	if got, _ := nf.FilterResult("暴力倾向"); !reflect.DeepEqual(got, map[string]int{"力倾": 1}) {
+		t.Errorf("filter after compact got %v", got)
+	}
+}
+
+func TestOutputLinks(t *testing.T) {
+	nf := NewNodeFilter([]string{"he", "she", "his", "hers"}).(*NodeFilter)
+
+	got, err := nf.FilterResult("ushers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := map[string]int{"she": 1, "he": 1, "hers": 1}
+	if !reflect.DeepEqual(expect, got) {
+		t.Errorf("filter result ushers, got %v, expect %v", got, expect)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	filtertest.Run(t, func(words []string) filter.SensitivewordFilter {
+		return NewNodeFilter(words)
+	})
+}
@@ -0,0 +1,55 @@
+package ac
+
+import (
+	"context"
+	"io"
+
+	"github.com/hellobchain/sensitivewordfilter/filter"
+)
+
+// FilterStream 实现filter.Streamer接口
+// 自动机只保留当前状态，以及当前状态深度以内已读入字符在原文中的下标
+func (nf *NodeFilter) FilterStream(ctx context.Context, reader io.Reader, fn func(filter.Match) bool) error {
+	var (
+		s       = filter.NewStream(ctx, reader, filter.NewMatchOptions(), nil)
+		n       = nf.root
+		history []int
+		matches []filter.Match
+	)
+	for {
+		r, i, err := s.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		matches = matches[:0]
+		nf.mux.RLock()
+		n = nf.next(n, r)
+		history = append(history, i)
+		if drop := len(history) - n.depth; drop > 0 {
+			history = history[drop:]
+		}
+		out := n
+		if !out.end {
+			out = out.output
+		}
+		for ; out != nil; out = out.output {
+			m := s.Match(history[len(history)-out.depth], i+1, nil)
+			m.Entry = out.word
+			matches = append(matches, m)
+		}
+		nf.mux.RUnlock()
+		for _, m := range matches {
+			if !fn(m) {
+				return nil
+			}
+		}
+		release := i + 1
+		if len(history) > 0 {
+			release = history[0]
+		}
+		s.Release(release)
+	}
+}
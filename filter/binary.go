@@ -0,0 +1,414 @@
+package filter
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"unsafe"
+)
+
+// 二进制词典的格式(整数均为小端序，变长整数使用uvarint编码)：
+//
+//	magic       4字节 "SWFD"
+//	version     uint16
+//	fingerprint uint64 创建过滤器时的配置指纹(OptionsFingerprint)
+//	strings     变长整数个数，每个字符串为变长整数长度及其内容
+//	metas       变长整数个数，每个词条为敏感词、分类、处理方式在字符串表中的下标及严重程度(变长整数)
+//	nodes       变长整数字节数，之后为先序排列的节点，每个节点为：
+//	            变长整数 字符(根节点为0)
+//	            1字节 标志，最低位表示词尾节点
+//	            词尾节点的敏感词及派生写法类型在字符串表中的下标(变长整数)
+//	            变长整数 子节点个数
+//	image       可选，以0填充到4字节对齐后的若干个uint32，为过滤器内存结构的映像(如双数组)，
+//	            可以不经解码直接引用(零拷贝)
+//	checksum    uint32 之前所有字节的CRC32(IEEE)
+//
+// 节点部分可用于重建任意实现的Trie，映像部分只有相同的实现可以使用
+const (
+	dictMagic = "SWFD"
+	// DictVersion 当前二进制词典格式的版本
+	DictVersion = 2
+
+	dictFlagEnd = 1
+	// dictHeader magic、version 及 fingerprint 的字节数
+	dictHeader = len(dictMagic) + 2 + 8
+)
+
+var (
+	// ErrDictFormat 二进制词典格式错误
+	ErrDictFormat = errors.New("filter: invalid dictionary format")
+	// ErrDictVersion 不支持的二进制词典版本
+	ErrDictVersion = errors.New("filter: unsupported dictionary version")
+	// ErrDictChecksum 二进制词典校验和不一致
+	ErrDictChecksum = errors.New("filter: dictionary checksum mismatch")
+	// ErrDictOptions 加载二进制词典时的配置(归一化、派生写法、间隔及整词匹配)与生成词典时不同
+	ErrDictOptions = errors.New("filter: dictionary built with different options")
+)
+
+// DictNode 二进制词典中的一个Trie节点
+type DictNode struct {
+	// Rune 父节点到该节点的字符，根节点为0
+	Rune rune
+	// End 是否为词尾节点
+	End bool
+	// Word 词尾节点对应的敏感词
+	Word string
+	// Variant 词尾节点对应的派生写法类型
+	Variant string
+	// Children 子节点个数，子节点紧随其后按先序写入
+	Children int
+}
+
+// DictEncoder 将Trie按先序编码为二进制词典
+type DictEncoder struct {
+	fingerprint uint64
+	index       map[string]uint64
+	table       []string
+	metas       []byte
+	nmetas      int
+	nodes       []byte
+	image       []uint32
+}
+
+// NewDictEncoder 创建二进制词典编码器，fingerprint 为创建过滤器时的配置指纹
+func NewDictEncoder(fingerprint uint64) *DictEncoder {
+	return &DictEncoder{fingerprint: fingerprint, index: make(map[string]uint64)}
+}
+
+// Node 写入一个节点，之后需依次写入其所有子节点
+func (e *DictEncoder) Node(n DictNode) {
+	e.nodes = appendUvarint(e.nodes, uint64(n.Rune))
+	if !n.End {
+		e.nodes = append(e.nodes, 0)
+	} else {
+		e.nodes = append(e.nodes, dictFlagEnd)
+		e.nodes = appendUvarint(e.nodes, e.intern(n.Word))
+		e.nodes = appendUvarint(e.nodes, e.intern(n.Variant))
+	}
+	e.nodes = appendUvarint(e.nodes, uint64(n.Children))
+}
+
+// Metas 写入词条的附加信息，按敏感词排序以保证相同的内容得到相同的词典
+func (e *DictEncoder) Metas(metas map[string]Meta) {
+	words := make([]string, 0, len(metas))
+	for word := range metas {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	for _, word := range words {
+		m := metas[word]
+		e.metas = appendUvarint(e.metas, e.intern(word))
+		e.metas = appendUvarint(e.metas, e.intern(m.Category))
+		e.metas = appendUvarint(e.metas, e.intern(m.Action))
+		e.metas = appendUvarint(e.metas, uint64(m.Severity))
+	}
+	e.nmetas += len(words)
+}
+
+// String 将 s 加入字符串表并返回其下标，供映像引用字符串
+func (e *DictEncoder) String(s string) uint32 {
+	return uint32(e.intern(s))
+}
+
+// Image 写入过滤器内存结构的映像
+func (e *DictEncoder) Image(image []uint32) {
+	e.image = image
+}
+
+// intern 返回字符串在字符串表中的下标，相同的字符串只保存一次
+func (e *DictEncoder) intern(s string) uint64 {
+	i, ok := e.index[s]
+	if !ok {
+		i = uint64(len(e.table))
+		e.index[s] = i
+		e.table = append(e.table, s)
+	}
+	return i
+}
+
+// Bytes 返回带版本号及校验和的二进制词典
+func (e *DictEncoder) Bytes() []byte {
+	data := make([]byte, dictHeader, dictHeader+len(e.metas)+len(e.nodes)+4*len(e.image)+16)
+	copy(data, dictMagic)
+	binary.LittleEndian.PutUint16(data[len(dictMagic):], DictVersion)
+	binary.LittleEndian.PutUint64(data[len(dictMagic)+2:], e.fingerprint)
+	data = appendUvarint(data, uint64(len(e.table)))
+	for _, s := range e.table {
+		data = appendUvarint(data, uint64(len(s)))
+		data = append(data, s...)
+	}
+	data = appendUvarint(data, uint64(e.nmetas))
+	data = append(data, e.metas...)
+	data = appendUvarint(data, uint64(len(e.nodes)))
+	data = append(data, e.nodes...)
+	if len(e.image) > 0 {
+		for len(data)%4 != 0 {
+			data = append(data, 0)
+		}
+		for _, v := range e.image {
+			data = append(data, 0, 0, 0, 0)
+			binary.LittleEndian.PutUint32(data[len(data)-4:], v)
+		}
+	}
+	sum := crc32.ChecksumIEEE(data)
+	data = append(data, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(data[len(data)-4:], sum)
+	return data
+}
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	var b [binary.MaxVarintLen64]byte
+	return append(buf, b[:binary.PutUvarint(b[:], v)]...)
+}
+
+// DictDecoder 按先序读取二进制词典中的节点
+type DictDecoder struct {
+	fingerprint uint64
+	data        []byte
+	table       []string
+	metas       map[string]Meta
+	image       []byte
+}
+
+// NewDictDecoder 校验二进制词典的版本号及校验和并读取字符串表及词条的附加信息
+func NewDictDecoder(data []byte) (*DictDecoder, error) {
+	if len(data) < dictHeader+4 || string(data[:len(dictMagic)]) != dictMagic {
+		return nil, ErrDictFormat
+	}
+	if binary.LittleEndian.Uint16(data[len(dictMagic):]) != DictVersion {
+		return nil, ErrDictVersion
+	}
+	body := data[:len(data)-4]
+	if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(data[len(body):]) {
+		return nil, ErrDictChecksum
+	}
+	d := &DictDecoder{
+		fingerprint: binary.LittleEndian.Uint64(data[len(dictMagic)+2:]),
+		data:        body[dictHeader:],
+	}
+	count, err := d.uvarint(uint64(len(d.data)))
+	if err != nil {
+		return nil, err
+	}
+	d.table = make([]string, count)
+	for i := range d.table {
+		l, err := d.uvarint(uint64(len(d.data)))
+		if err != nil {
+			return nil, err
+		}
+		d.table[i] = string(d.data[:l])
+		d.data = d.data[l:]
+	}
+	// 每个词条至少占用4个字节
+	if count, err = d.uvarint(uint64(len(d.data) / 4)); err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		d.metas = make(map[string]Meta, count)
+	}
+	for i := uint64(0); i < count; i++ {
+		var word string
+		var m Meta
+		if word, err = d.string(); err != nil {
+			return nil, err
+		}
+		if m.Category, err = d.string(); err != nil {
+			return nil, err
+		}
+		if m.Action, err = d.string(); err != nil {
+			return nil, err
+		}
+		severity, err := d.uvarint(1<<31 - 1)
+		if err != nil {
+			return nil, err
+		}
+		m.Severity = int(severity)
+		d.metas[word] = m
+	}
+	size, err := d.uvarint(uint64(len(d.data)))
+	if err != nil {
+		return nil, err
+	}
+	if rest := d.data[size:]; len(rest) > 0 {
+		// 映像相对词典开头4字节对齐
+		pad := (4 - (len(body)-len(rest))%4) % 4
+		if len(rest) < pad || (len(rest)-pad)%4 != 0 {
+			return nil, ErrDictFormat
+		}
+		d.image = rest[pad:]
+	}
+	d.data = d.data[:size]
+	return d, nil
+}
+
+// Fingerprint 生成词典时的配置指纹
+func (d *DictDecoder) Fingerprint() uint64 {
+	return d.fingerprint
+}
+
+// Metas 词条的附加信息，没有时返回nil
+func (d *DictDecoder) Metas() map[string]Meta {
+	return d.metas
+}
+
+// Image 过滤器内存结构的映像，没有时返回nil
+// 返回的切片引用词典的数据，相对词典开头4字节对齐
+func (d *DictDecoder) Image() []byte {
+	return d.image
+}
+
+// String 返回字符串表中下标为 i 的字符串
+func (d *DictDecoder) String(i uint32) (string, error) {
+	if uint64(i) >= uint64(len(d.table)) {
+		return "", ErrDictFormat
+	}
+	return d.table[i], nil
+}
+
+// Node 读取下一个节点
+func (d *DictDecoder) Node() (DictNode, error) {
+	var n DictNode
+	r, err := d.uvarint(0x10FFFF)
+	if err != nil {
+		return n, err
+	}
+	n.Rune = rune(r)
+	if len(d.data) == 0 {
+		return n, ErrDictFormat
+	}
+	flags := d.data[0]
+	d.data = d.data[1:]
+	if flags&dictFlagEnd != 0 {
+		n.End = true
+		if n.Word, err = d.string(); err != nil {
+			return n, err
+		}
+		if n.Variant, err = d.string(); err != nil {
+			return n, err
+		}
+	}
+	// 每个子节点至少占用3个字节
+	children, err := d.uvarint(uint64(len(d.data) / 3))
+	if err != nil {
+		return n, err
+	}
+	n.Children = int(children)
+	return n, nil
+}
+
+// Done 检查所有节点都已读取
+func (d *DictDecoder) Done() error {
+	if len(d.data) != 0 {
+		return ErrDictFormat
+	}
+	return nil
+}
+
+func (d *DictDecoder) string() (string, error) {
+	i, err := d.uvarint(uint64(len(d.table)))
+	if err != nil || i == uint64(len(d.table)) {
+		return "", ErrDictFormat
+	}
+	return d.table[i], nil
+}
+
+// uvarint 读取不大于 max 的变长整数
+func (d *DictDecoder) uvarint(max uint64) (uint64, error) {
+	v, n := binary.Uvarint(d.data)
+	if n <= 0 || v > max {
+		return 0, ErrDictFormat
+	}
+	d.data = d.data[n:]
+	return v, nil
+}
+
+// Uint32s 将映像中的字节按小端序转换为uint32
+// 本机为小端序且数据4字节对齐时直接引用 b 的内存(零拷贝)，返回的切片不可修改；否则复制，shared 为false
+func Uint32s(b []byte) (words []uint32, shared bool) {
+	n := len(b) / 4
+	if n == 0 {
+		return nil, false
+	}
+	if nativeLittleEndian && uintptr(unsafe.Pointer(&b[0]))%4 == 0 {
+		return (*[1 << 28]uint32)(unsafe.Pointer(&b[0]))[:n:n], true
+	}
+	words = make([]uint32, n)
+	for i := range words {
+		words[i] = binary.LittleEndian.Uint32(b[4*i:])
+	}
+	return words, false
+}
+
+var nativeLittleEndian = func() bool {
+	x := uint16(1)
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}()
+
+// fingerprintProbe 计算配置指纹时使用的样本文本，覆盖大小写、全角、变音符号、形近字符、火星文及繁简汉字
+const fingerprintProbe = "AaZz09 ＡＢｃ１２ éüñçÀß аеорсх 0134578@$!+|€£ 臺灣台湾暴力銀行微信 ①ﬁ㎏ ｶﾞ"
+
+// fingerprintWords 计算配置指纹时用于派生写法的样本词
+var fingerprintWords = []string{"暴力", "台独", "微信", "银行", "shit"}
+
+// OptionsFingerprint 返回影响词典内容及匹配结果的配置的指纹，包括归一化、派生写法、间隔及整词匹配
+// 归一化及派生写法无法直接比较，按其对样本文本的处理结果计算，样本以外的差异无法发现
+func OptionsFingerprint(o *Options) uint64 {
+	h := fnv.New64a()
+	write := func(s string) {
+		_, _ = h.Write([]byte(s))
+		_, _ = h.Write([]byte{0})
+	}
+	write(NormalizeString(o.Normalizer, fingerprintProbe))
+	for _, word := range fingerprintWords {
+		for _, v := range o.Expand(word) {
+			write(v.Kind)
+			write(NormalizeString(o.Normalizer, v.Text))
+		}
+	}
+	write(strconv.Itoa(o.MaxGap))
+	if o.MaxGap > 0 && o.GapClass != nil {
+		class := make([]byte, 0, len(fingerprintProbe))
+		for _, r := range fingerprintProbe {
+			if o.GapClass(r) {
+				class = append(class, '1')
+			} else {
+				class = append(class, '0')
+			}
+		}
+		write(string(class))
+	}
+	write(strconv.FormatBool(o.WholeWord))
+	return h.Sum64()
+}
+
+// DictWriter 可以写入二进制词典的过滤器
+type DictWriter interface {
+	// EncodeDict 返回写入过滤器的配置指纹及所有节点的编码器
+	EncodeDict() *DictEncoder
+}
+
+// ErrDictUnsupported 过滤器不支持二进制词典
+var ErrDictUnsupported = errors.New("filter: filter does not support binary dictionary")
+
+// DecodeDict 校验二进制词典，并检查生成词典时的配置指纹与加载时的配置 o 相同
+func DecodeDict(data []byte, o *Options) (*DictDecoder, error) {
+	d, err := NewDictDecoder(data)
+	if err != nil {
+		return nil, err
+	}
+	if d.Fingerprint() != OptionsFingerprint(o) {
+		return nil, ErrDictOptions
+	}
+	return d, nil
+}
+
+// WrapDictMetas 二进制词典包含词条的附加信息时，使用 NewMetaFilter 包装加载的过滤器 f
+func WrapDictMetas(f MatchFilter, d *DictDecoder) SensitivewordFilter {
+	if metas := d.Metas(); len(metas) > 0 {
+		return NewMetaFilter(f, metas)
+	}
+	return f
+}
@@ -0,0 +1,174 @@
+package filter_test
+
+import (
+	"bytes"
+	"encoding"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hellobchain/sensitivewordfilter/filter"
+	"github.com/hellobchain/sensitivewordfilter/filter/dfa"
+	"github.com/hellobchain/sensitivewordfilter/filter/newdfa"
+	"github.com/hellobchain/sensitivewordfilter/filter/pinyin"
+)
+
+func TestMarshalBinary(t *testing.T) {
+	type loader func(data []byte) (filter.SensitivewordFilter, error)
+	words := []string{"暴力", "暴力倾向", "广告"}
+	filters := map[string]struct {
+		f    filter.SensitivewordFilter
+		load loader
+	}{
+		"dfa": {
+			f: dfa.NewNodeFilter(words, pinyin.WithPinyin()),
+			load: func(data []byte) (filter.SensitivewordFilter, error) {
+				return dfa.LoadNodeFilter(bytes.NewReader(data), pinyin.WithPinyin())
+			},
+		},
+		"newdfa": {
+			f: newdfa.NewNodeFilter(words, pinyin.WithPinyin()),
+			load: func(data []byte) (filter.SensitivewordFilter, error) {
+				return newdfa.LoadNodeFilter(bytes.NewReader(data), pinyin.WithPinyin())
+			},
+		},
+		"doublearray": {
+			f: newdfa.NewNodeFilter(words, pinyin.WithPinyin(), filter.WithDoubleArray()),
+			load: func(data []byte) (filter.SensitivewordFilter, error) {
+				return newdfa.LoadNodeFilter(bytes.NewReader(data), pinyin.WithPinyin(), filter.WithDoubleArray())
+			},
+		},
+	}
+	const text = "暴力倾向的guang gao"
+	for name, c := range filters {
+		t.Run(name, func(t *testing.T) {
+			data, err := c.f.(encoding.BinaryMarshaler).MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			again, _ := c.f.(encoding.BinaryMarshaler).MarshalBinary()
+			if !bytes.Equal(data, again) {
+				t.Errorf("expect deterministic encoding")
+			}
+			loaded, err := c.load(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expect, _ := c.f.(filter.Matcher).FindMatches(text)
+			got, _ := loaded.(filter.Matcher).FindMatches(text)
+			if len(expect) == 0 || !reflect.DeepEqual(got, expect) {
+				t.Errorf("got %+v, expect %+v", got, expect)
+			}
+
+			corrupted := append([]byte(nil), data...)
+			corrupted[len(corrupted)/2] ^= 0xff
+			if _, err := c.load(corrupted); err != filter.ErrDictChecksum {
+				t.Errorf("expect checksum mismatch, got %v", err)
+			}
+			newer := append([]byte(nil), data...)
+			newer[4] = filter.DictVersion + 1
+			if _, err := c.load(newer); err != filter.ErrDictVersion {
+				t.Errorf("expect unsupported version, got %v", err)
+			}
+			if _, err := c.load(data[:3]); err != filter.ErrDictFormat {
+				t.Errorf("expect invalid format, got %v", err)
+			}
+		})
+	}
+}
+
+func TestMarshalBinaryOptions(t *testing.T) {
+	f := newdfa.NewNodeFilter([]string{"暴力"}, filter.WithNormalizer(filter.CaseFold))
+	data, _ := f.(encoding.BinaryMarshaler).MarshalBinary()
+	// 归一化、派生写法、间隔及整词匹配不同时拒绝加载
+	for name, opts := range map[string][]filter.Option{
+		"normalizer": nil,
+		"pinyin":     {filter.WithNormalizer(filter.CaseFold), pinyin.WithPinyin()},
+		"gap":        {filter.WithNormalizer(filter.CaseFold), filter.WithMaxGap(2, nil)},
+		"wholeword":  {filter.WithNormalizer(filter.CaseFold), filter.WithWholeWord()},
+	} {
+		if _, err := newdfa.LoadNodeFilter(bytes.NewReader(data), opts...); err != filter.ErrDictOptions {
+			t.Errorf("%s: expect options mismatch, got %v", name, err)
+		}
+		if _, err := dfa.LoadNodeFilter(bytes.NewReader(data), opts...); err != filter.ErrDictOptions {
+			t.Errorf("%s: dfa expect options mismatch, got %v", name, err)
+		}
+	}
+	// 双数组与Trie的配置指纹相同
+	if _, err := newdfa.LoadNodeFilter(bytes.NewReader(data), filter.WithNormalizer(filter.CaseFold), filter.WithDoubleArray()); err != nil {
+		t.Errorf("expect dictionary loaded into double array, got %v", err)
+	}
+}
+
+func TestMarshalBinaryMetas(t *testing.T) {
+	metas := map[string]filter.Meta{"广告": {Category: "ads", Severity: 2, Action: "review"}}
+	for name, f := range map[string]filter.MatchFilter{
+		"dfa":    dfa.NewNodeFilter([]string{"暴力", "广告"}).(filter.MatchFilter),
+		"newdfa": newdfa.NewNodeFilter([]string{"暴力", "广告"}).(filter.MatchFilter),
+	} {
+		data, err := filter.NewMetaFilter(f, metas).MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		load := dfa.LoadNodeFilter
+		if name == "newdfa" {
+			load = newdfa.LoadNodeFilter
+		}
+		loaded, err := load(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		matches, _ := loaded.(filter.Matcher).FindMatches("暴力广告")
+		if len(matches) != 2 || matches[0].Meta != (filter.Meta{}) || matches[1].Meta != metas["广告"] {
+			t.Errorf("%s: expect metas loaded, got %+v", name, matches)
+		}
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	f := newdfa.NewNodeFilter([]string{"暴力", "广告", "𠮷野家"}, filter.WithDoubleArray())
+	data, _ := f.(encoding.BinaryMarshaler).MarshalBinary()
+	path := filepath.Join(t.TempDir(), "dict.swfd")
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := newdfa.LoadFile(path, filter.WithDoubleArray())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.IsExist("吃𠮷野家") || loaded.IsExist("暴") {
+		t.Errorf("unexpected matches after mapped load")
+	}
+	// 映射的数据只读，增删敏感词前复制
+	loaded.Add("赌博")
+	loaded.Remove("广告")
+	if !loaded.IsExist("赌博") || loaded.IsExist("广告") || !loaded.IsExist("暴力") {
+		t.Errorf("unexpected matches after updating mapped filter")
+	}
+	again, _ := loaded.(encoding.BinaryMarshaler).MarshalBinary()
+	if mapped, err := newdfa.LoadFile(path, filter.WithDoubleArray()); err != nil || !mapped.IsExist("广告") || bytes.Equal(again, data) {
+		t.Errorf("expect file unchanged by updates, got %v", err)
+	}
+	if _, err := newdfa.LoadFile(path); err != nil {
+		t.Errorf("expect dictionary loaded into trie, got %v", err)
+	}
+}
+
+func TestDictDecoderTruncated(t *testing.T) {
+	e := filter.NewDictEncoder(0)
+	e.Node(filter.DictNode{Children: 2})
+	e.Node(filter.DictNode{Rune: '暴', End: true, Word: "暴"})
+	d, err := filter.NewDictDecoder(e.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := d.Node(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := d.Node(); err != filter.ErrDictFormat {
+		t.Errorf("expect invalid format for missing node, got %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package filter
+
+import "unicode"
+
+// BoundaryContext 判断单词边界最多需要边界之前或之后的字符个数
+const BoundaryContext = 2
+
+// noBoundaryScripts 词之间没有空白、不按单词边界匹配的文字，这些文字的敏感词仍按子串匹配
+var noBoundaryScripts = []*unicode.RangeTable{
+	unicode.Han, unicode.Hiragana, unicode.Katakana,
+	unicode.Thai, unicode.Lao, unicode.Khmer, unicode.Myanmar,
+}
+
+// isWordRune 是否为拉丁、西里尔等以空白分词的文字中组成单词的字符
+func isWordRune(r rune) bool {
+	return (unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) || r == '_') &&
+		!unicode.In(r, noBoundaryScripts...)
+}
+
+// isMidLetter 字母之间不断开单词的字符，如 "can't"
+func isMidLetter(r rune) bool {
+	switch r {
+	case '\'', '.', ':', '·', '’', '‧':
+		return true
+	}
+	return false
+}
+
+// isMidNum 数字之间不断开单词的字符，如 "3.14"、"1,000"
+func isMidNum(r rune) bool {
+	switch r {
+	case '\'', '.', ',', ';', '’':
+		return true
+	}
+	return false
+}
+
+// joined a、b 是否在中间字符 mid 两侧组成同一个单词
+func joined(a, mid, b rune) bool {
+	if unicode.IsDigit(a) && unicode.IsDigit(b) {
+		return isMidNum(mid)
+	}
+	return isMidLetter(mid) && isWordRune(a) && !unicode.IsDigit(a) && isWordRune(b) && !unicode.IsDigit(b)
+}
+
+// IsWordBoundary 判断 runes 中第 i-1 与第 i 个字符之间是否为单词边界，i 为0或len(runes)时为边界
+// 按UAX #29的简化规则：组成单词的字母、数字及组合符号之间不断开，中间字符(如 "can't" 中的 "'")两侧为字母或数字时也不断开
+func IsWordBoundary(runes []rune, i int) bool {
+	if i <= 0 || i >= len(runes) {
+		return true
+	}
+	a, b := runes[i-1], runes[i]
+	if isWordRune(a) && isWordRune(b) {
+		return false
+	}
+	if isWordRune(a) && i+1 < len(runes) && joined(a, b, runes[i+1]) {
+		return false
+	}
+	if isWordRune(b) && i >= 2 && joined(runes[i-2], a, b) {
+		return false
+	}
+	return true
+}
+
+// IsWholeWord 判断区间 [start, end) 是否满足整词匹配
+// 区间以拉丁、西里尔等文字的单词字符开头(结尾)时，之前(之后)需为单词边界；以汉字等其它字符开头(结尾)时不作要求
+func IsWholeWord(runes []rune, start, end int) bool {
+	return start >= end || (IsWordStart(runes, start) && IsWordEnd(runes, end))
+}
+
+// IsWordStart 判断以 runes[start] 开头的区间是否满足整词匹配的开头
+func IsWordStart(runes []rune, start int) bool {
+	return !isWordRune(runes[start]) || IsWordBoundary(runes, start)
+}
+
+// IsWordEnd 判断以 runes[end-1] 结尾的区间是否满足整词匹配的结尾
+func IsWordEnd(runes []rune, end int) bool {
+	return !isWordRune(runes[end-1]) || IsWordBoundary(runes, end)
+}
+
+// IsSeparator 判断字符 r 是否为切分文本的空白或标点
+// 经归一化 n 折叠为字母或数字的字符(如火星文中的 "@"、"!")参与匹配，不作为分隔字符
+func IsSeparator(n Normalizer, r rune) bool {
+	if !unicode.IsSpace(r) && !unicode.IsPunct(r) {
+		return false
+	}
+	if n == nil {
+		return true
+	}
+	for _, c := range n.Normalize(nil, r) {
+		if unicode.IsLetter(c) || unicode.IsDigit(c) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,92 @@
+package filter_test
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hellobchain/sensitivewordfilter/filter"
+	"github.com/hellobchain/sensitivewordfilter/filter/newdfa"
+)
+
+func TestWholeWord(t *testing.T) {
+	words := []string{"ass", "can", "cunt", "3.1", "傻瓜"}
+	testcases := []struct {
+		Text   string
+		Expect []string
+	}{
+		{"first class", nil},
+		{"Scunthorpe", nil},
+		{"you ass!", []string{"ass"}},
+		{"I can't", nil},
+		{"I can do it", []string{"can"}},
+		{"pi is 3.14", nil},
+		{"3.1 inch", []string{"3.1"}},
+		// 汉字仍按子串匹配
+		{"你这个傻瓜蛋", []string{"傻瓜"}},
+		{"ass傻瓜", []string{"ass", "傻瓜"}},
+	}
+	for name, opts := range map[string][]filter.Option{
+		"trie":        {filter.WithWholeWord()},
+		"doublearray": {filter.WithWholeWord(), filter.WithDoubleArray()},
+	} {
+		f := newdfa.NewNodeFilter(words, opts...)
+		for _, tc := range testcases {
+			matches, err := f.(filter.Matcher).FindMatches(tc.Text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, m := range matches {
+				got = append(got, m.Entry)
+			}
+			if !reflect.DeepEqual(got, tc.Expect) {
+				t.Errorf("%s FindMatches(%q) = %v, want %v", name, tc.Text, got, tc.Expect)
+			}
+			result, _ := f.Filter(tc.Text)
+			sort.Strings(result)
+			expect := append([]string(nil), tc.Expect...)
+			sort.Strings(expect)
+			if len(result) != len(expect) || (len(expect) > 0 && !reflect.DeepEqual(result, expect)) {
+				t.Errorf("%s Filter(%q) = %v, want %v", name, tc.Text, result, expect)
+			}
+			if exist := f.IsExist(tc.Text); exist != (len(tc.Expect) > 0) {
+				t.Errorf("%s IsExist(%q) = %v", name, tc.Text, exist)
+			}
+		}
+	}
+}
+
+func TestWholeWordStream(t *testing.T) {
+	f := newdfa.NewNodeFilter([]string{"ass", "傻瓜"}, filter.WithWholeWord())
+	text := "first class, you ass, 傻瓜蛋 glass"
+	var got []string
+	err := f.(filter.Streamer).FilterStream(context.Background(), strings.NewReader(text), func(m filter.Match) bool {
+		got = append(got, m.Word)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := []string{"ass", "傻瓜"}; !reflect.DeepEqual(got, expect) {
+		t.Errorf("FilterStream = %v, want %v", got, expect)
+	}
+
+	// 逐字节写入时单词边界同样跨越多次写入判断
+	var buf bytes.Buffer
+	w := filter.NewReplacingWriter(&buf, f)
+	for i := 0; i < len(text); i++ {
+		if _, err := w.Write([]byte{text[i]}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if expect := "first class, you ***, **蛋 glass"; buf.String() != expect {
+		t.Errorf("ReplacingWriter = %q", buf.String())
+	}
+}
@@ -0,0 +1,93 @@
+package filter_test
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/hellobchain/sensitivewordfilter/filter"
+	"github.com/hellobchain/sensitivewordfilter/filter/ac"
+	"github.com/hellobchain/sensitivewordfilter/filter/dfa"
+	"github.com/hellobchain/sensitivewordfilter/filter/newdfa"
+	"github.com/hellobchain/sensitivewordfilter/filter/pinyin"
+	"github.com/hellobchain/sensitivewordfilter/filter/rule"
+)
+
+// TestConcurrentAddRemove 并发查找及增删敏感词，需配合 -race 运行
+func TestConcurrentAddRemove(t *testing.T) {
+	words := []string{"暴力", "广告", "赌博"}
+	filters := map[string]func() filter.SensitivewordFilter{
+		"dfa":         func() filter.SensitivewordFilter { return dfa.NewNodeFilter(words) },
+		"newdfa":      func() filter.SensitivewordFilter { return newdfa.NewNodeFilter(words) },
+		"pinyin":      func() filter.SensitivewordFilter { return newdfa.NewNodeFilter(words, pinyin.WithPinyin()) },
+		"doublearray": func() filter.SensitivewordFilter { return newdfa.NewNodeFilter(words, filter.WithDoubleArray()) },
+		"ac":          func() filter.SensitivewordFilter { return ac.NewNodeFilter(words) },
+		"rule":        func() filter.SensitivewordFilter { return rule.NewNodeFilter(words) },
+	}
+	for name, newFilter := range filters {
+		t.Run(name, func(t *testing.T) {
+			f := newFilter()
+			var wg sync.WaitGroup
+			for g := 0; g < 4; g++ {
+				wg.Add(2)
+				go func(g int) {
+					defer wg.Done()
+					for i := 0; i < 200; i++ {
+						word := "敏感" + strconv.Itoa(g) + strconv.Itoa(i%10)
+						f.Add(word)
+						f.Remove(word)
+					}
+				}(g)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < 200; i++ {
+						if _, err := f.Filter("不要暴力，敏感01"); err != nil {
+							t.Error(err)
+							return
+						}
+						if _, err := f.Replace("不要暴力，敏感02", '*'); err != nil {
+							t.Error(err)
+							return
+						}
+						if !f.IsExist("不要暴力") {
+							t.Errorf("expect existing word found during concurrent updates")
+							return
+						}
+					}
+				}()
+			}
+			wg.Wait()
+		})
+	}
+}
+
+// TestConcurrentUpdate 增量更新后分别修改新旧过滤器，两者互不影响
+func TestConcurrentUpdate(t *testing.T) {
+	for name, f := range map[string]filter.SensitivewordFilter{
+		"dfa":         dfa.NewNodeFilter([]string{"暴力"}),
+		"newdfa":      newdfa.NewNodeFilter([]string{"暴力"}),
+		"doublearray": newdfa.NewNodeFilter([]string{"暴力"}, filter.WithDoubleArray()),
+	} {
+		updated := f.(filter.Updater).Update([]string{"广告"}, nil)
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				f.Add("赌博" + strconv.Itoa(i))
+				updated.IsExist("赌博1")
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				updated.Add("色情" + strconv.Itoa(i))
+				f.IsExist("色情1")
+			}
+		}()
+		wg.Wait()
+		if updated.IsExist("赌博1") || f.IsExist("色情1") || f.IsExist("广告") {
+			t.Errorf("%s expect filters independent after update", name)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// Package confusable 提供形近字符(Unicode TR39 confusables)及火星文(leetspeak)的折叠，
+// 用于检测以形近字符或数字、符号替代字母规避过滤的文本
+package confusable
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hellobchain/sensitivewordfilter/filter"
+)
+
+//go:embed confusables.txt
+var confusablesData string
+
+// Mapping 字符折叠映射表，将源字符映射为一个或多个目标字符
+// Mapping 实现了filter.Normalizer接口
+type Mapping map[rune]string
+
+// Normalize 实现filter.Normalizer接口
+func (m Mapping) Normalize(dst []rune, r rune) []rune {
+	if s, ok := m[r]; ok {
+		for _, v := range s {
+			dst = append(dst, v)
+		}
+		return dst
+	}
+	return append(dst, r)
+}
+
+// Merge 合并多个映射表，后面的映射表覆盖前面相同的源字符，返回新的映射表
+func (m Mapping) Merge(others ...Mapping) Mapping {
+	result := make(Mapping, len(m))
+	for k, v := range m {
+		result[k] = v
+	}
+	for _, o := range others {
+		for k, v := range o {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// Confusables 基于Unicode TR39的形近字符映射表，如西里尔字母 "а" 映射为拉丁字母 "a"
+func Confusables() Mapping {
+	m, err := Load(strings.NewReader(confusablesData))
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// Leetspeak 默认的火星文映射表，如 "4" 映射为 "a"，"$" 映射为 "s"
+func Leetspeak() Mapping {
+	return Mapping{
+		'0': "o",
+		'1': "i",
+		'3': "e",
+		'4': "a",
+		'5': "s",
+		'7': "t",
+		'8': "b",
+		'9': "g",
+		'@': "a",
+		'$': "s",
+		'!': "i",
+		'+': "t",
+		'|': "l",
+		'€': "e",
+		'£': "l",
+	}
+}
+
+// Load 从可读流中加载映射表
+// 每行格式为 "源字符 目标字符"，以空白分隔，"#" 之后的内容为注释
+// 字符可以直接书写，也可以使用 "U+0430" 的形式，目标字符可以是多个字符
+func Load(rd io.Reader) (Mapping, error) {
+	m := make(Mapping)
+	scanner := bufio.NewScanner(rd)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("confusable: 第%d行格式错误: %q", lineNo, scanner.Text())
+		}
+		src, err := parseChars(fields[0])
+		if err != nil || utf8.RuneCountInString(src) != 1 {
+			return nil, fmt.Errorf("confusable: 第%d行源字符错误: %q", lineNo, fields[0])
+		}
+		dst, err := parseChars(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("confusable: 第%d行目标字符错误: %q", lineNo, fields[1])
+		}
+		r, _ := utf8.DecodeRuneInString(src)
+		m[r] = dst
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// LoadFile 从文件中加载映射表，文件格式参见Load
+func LoadFile(path string) (Mapping, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Load(f)
+}
+
+// parseChars 解析直接书写或以 "U+XXXX" 形式书写的字符
+func parseChars(s string) (string, error) {
+	if !strings.HasPrefix(s, "U+") && !strings.HasPrefix(s, "u+") {
+		return s, nil
+	}
+	var sb strings.Builder
+	for _, part := range strings.Split(strings.ToUpper(s[2:]), "U+") {
+		v, err := strconv.ParseUint(part, 16, 32)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteRune(rune(v))
+	}
+	return sb.String(), nil
+}
+
+// WithMapping 开启字符折叠，敏感词及待检测文本在匹配前都经过映射表折叠
+// 未指定映射表时使用形近字符及默认火星文映射表
+func WithMapping(mappings ...Mapping) filter.Option {
+	if len(mappings) == 0 {
+		mappings = []Mapping{Confusables(), Leetspeak()}
+	}
+	return filter.AppendNormalizer(Mapping{}.Merge(mappings...))
+}
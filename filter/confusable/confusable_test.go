@@ -0,0 +1,67 @@
+package confusable
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hellobchain/sensitivewordfilter/filter"
+	"github.com/hellobchain/sensitivewordfilter/filter/dfa"
+	"github.com/hellobchain/sensitivewordfilter/filter/newdfa"
+)
+
+func TestLoad(t *testing.T) {
+	m, err := Load(strings.NewReader("# comment\nU+0430 a\n" + "ph f # digraph target\n\n3 e\n"))
+	if err == nil {
+		t.Fatalf("expect error for multi-rune source, got %v", m)
+	}
+
+	m, err = Load(strings.NewReader("# comment\nU+0430 a\nß ss\n3\te # leet\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := Mapping{'а': "a", 'ß': "ss", '3': "e"}
+	if !reflect.DeepEqual(expect, m) {
+		t.Errorf("load got %v, expect %v", m, expect)
+	}
+}
+
+func TestWithMapping(t *testing.T) {
+	opts := []filter.Option{filter.WithNormalizer(filter.CaseFold), WithMapping()}
+	filters := map[string]filter.SensitivewordFilter{
+		"dfa":    dfa.NewNodeFilter([]string{"ass", "hate", "shit"}, opts...),
+		"newdfa": newdfa.NewNodeFilter([]string{"ass", "hate", "shit"}, opts...),
+	}
+
+	for name, f := range filters {
+		got, err := f.FilterResult("аss h4te")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expect := map[string]int{"ass": 1, "hate": 1}; !reflect.DeepEqual(expect, got) {
+			t.Errorf("%s filter result got %v, expect %v", name, got, expect)
+		}
+		if replaced, _ := f.Replace("I H4TE аss", '*'); replaced != "I **** ***" {
+			t.Errorf("%s replace got %s, expect I **** ***", name, replaced)
+		}
+		// 映射为字母的标点不切分文本
+		if !f.IsExist("sh!t") {
+			t.Errorf("%s expect sh!t exist", name)
+		}
+		if got, _ := f.FilterResult("I h@te it!"); !reflect.DeepEqual(got, map[string]int{"hate": 1}) {
+			t.Errorf("%s filter result got %v, expect h@te folded", name, got)
+		}
+		matches, _ := f.(filter.Matcher).FindMatches("$HIT")
+		if len(matches) != 1 || matches[0].Word != "$HIT" || matches[0].Entry != "shit" {
+			t.Errorf("%s find matches got %+v", name, matches)
+		}
+	}
+}
+
+func TestCustomMapping(t *testing.T) {
+	custom := Mapping{'v': "u"}
+	f := newdfa.NewNodeFilter([]string{"fuck"}, WithMapping(Leetspeak(), custom))
+	if !f.IsExist("fvck") {
+		t.Errorf("expect custom mapping applied")
+	}
+}
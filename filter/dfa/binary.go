@@ -0,0 +1,106 @@
+package dfa
+
+import (
+	"io"
+	"sort"
+
+	"github.com/hellobchain/sensitivewordfilter/filter"
+)
+
+// LoadNodeFilter 从可读流中读取MarshalBinary生成的二进制词典，创建节点过滤器
+// 加载时不再对敏感词进行归一化及派生，opts 中的归一化、派生写法、间隔及整词匹配需与生成词典时一致，否则返回 filter.ErrDictOptions；
+// 词典包含词条的附加信息时返回携带附加信息的 filter.MetaFilter
+// 节点使用map存储，需要逐个解码，需要零拷贝加载时使用 newdfa 的双数组
+func LoadNodeFilter(rd io.Reader, opts ...filter.Option) (filter.SensitivewordFilter, error) {
+	data, err := io.ReadAll(rd)
+	if err != nil {
+		return nil, err
+	}
+	nf := newNodeFilter(opts...)
+	d, err := filter.DecodeDict(data, nf.options)
+	if err != nil {
+		return nil, err
+	}
+	if err := nf.decode(d); err != nil {
+		return nil, err
+	}
+	return filter.WrapDictMetas(nf, d), nil
+}
+
+// MarshalBinary 实现encoding.BinaryMarshaler接口
+// 将所有节点(包括词尾节点对应的敏感词及派生写法类型)编码为带版本号、配置指纹及校验和的二进制词典，
+// 需同时保存词条的附加信息时使用 filter.MetaFilter 的 MarshalBinary
+func (nf *NodeFilter) MarshalBinary() ([]byte, error) {
+	return nf.EncodeDict().Bytes(), nil
+}
+
+// EncodeDict 实现filter.DictWriter接口，写入创建过滤器时的配置指纹及所有节点
+func (nf *NodeFilter) EncodeDict() *filter.DictEncoder {
+	nf.mux.RLock()
+	defer nf.mux.RUnlock()
+	e := filter.NewDictEncoder(filter.OptionsFingerprint(nf.options))
+	var encode func(r rune, n *node)
+	encode = func(r rune, n *node) {
+		e.Node(filter.DictNode{Rune: r, End: n.end, Word: n.word, Variant: n.variant, Children: len(n.child)})
+		runes := make([]rune, 0, len(n.child))
+		for r := range n.child {
+			runes = append(runes, r)
+		}
+		sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+		for _, r := range runes {
+			encode(r, n.child[r])
+		}
+	}
+	encode(0, nf.root)
+	return e
+}
+
+// UnmarshalBinary 实现encoding.BinaryUnmarshaler接口，以二进制词典中的节点替换过滤器的所有敏感词
+// 词典无效或生成词典时的配置与过滤器不同时返回error，过滤器保持不变；词典中词条的附加信息被忽略
+func (nf *NodeFilter) UnmarshalBinary(data []byte) error {
+	if nf.options == nil {
+		*nf = *newNodeFilter()
+	}
+	d, err := filter.DecodeDict(data, nf.options)
+	if err != nil {
+		return err
+	}
+	return nf.decode(d)
+}
+
+// decode 以词典中的节点替换过滤器的所有敏感词，词典无效时返回error，过滤器保持不变
+func (nf *NodeFilter) decode(d *filter.DictDecoder) error {
+	var decode func() (rune, *node, error)
+	decode = func() (rune, *node, error) {
+		dn, err := d.Node()
+		if err != nil {
+			return 0, nil, err
+		}
+		n := &node{
+			end:     dn.End,
+			word:    dn.Word,
+			variant: dn.Variant,
+			child:   make(map[rune]*node, dn.Children),
+		}
+		for i := 0; i < dn.Children; i++ {
+			r, next, err := decode()
+			if err != nil {
+				return 0, nil, err
+			}
+			n.child[r] = next
+		}
+		return dn.Rune, n, nil
+	}
+	_, root, err := decode()
+	if err != nil {
+		return err
+	}
+	if err := d.Done(); err != nil {
+		return err
+	}
+	nf.mux.Lock()
+	defer nf.mux.Unlock()
+	nf.root = root
+	nf.owned = nil
+	return nil
+}
@@ -4,7 +4,9 @@ import (
 	"bufio"
 	"bytes"
 	"regexp"
-	"unicode"
+	"strings"
+	"sync"
+	"unsafe"
 
 	"io"
 
@@ -13,8 +15,8 @@ import (
 
 // NewNodeReaderFilter 创建节点过滤器，实现敏感词的过滤
 // 从可读流中读取敏感词数据(以指定的分隔符读取数据)
-func NewNodeReaderFilter(rd io.Reader, delim byte) filter.SensitivewordFilter {
-	nf := newNodeFilter()
+func NewNodeReaderFilter(rd io.Reader, delim byte, opts ...filter.Option) filter.SensitivewordFilter {
+	nf := newNodeFilter(opts...)
 	buf := new(bytes.Buffer)
 	_, _ = io.Copy(buf, rd)
 	buf.WriteByte(delim)
@@ -34,8 +36,8 @@ func NewNodeReaderFilter(rd io.Reader, delim byte) filter.SensitivewordFilter {
 
 // NewNodeChanFilter 创建节点过滤器，实现敏感词的过滤
 // 从通道中读取敏感词数据
-func NewNodeChanFilter(text <-chan string) filter.SensitivewordFilter {
-	nf := newNodeFilter()
+func NewNodeChanFilter(text <-chan string, opts ...filter.Option) filter.SensitivewordFilter {
+	nf := newNodeFilter(opts...)
 	for v := range text {
 		nf.addSensitivewords(v)
 	}
@@ -44,8 +46,8 @@ func NewNodeChanFilter(text <-chan string) filter.SensitivewordFilter {
 
 // NewNodeFilter 创建节点过滤器，实现敏感词的过滤
 // 从切片中读取敏感词数据
-func NewNodeFilter(text []string) filter.SensitivewordFilter {
-	nf := newNodeFilter()
+func NewNodeFilter(text []string, opts ...filter.Option) filter.SensitivewordFilter {
+	nf := newNodeFilter(opts...)
 	for i, l := 0, len(text); i < l; i++ {
 		nf.addSensitivewords(text[i])
 	}
@@ -59,13 +61,30 @@ func newNode() *node {
 }
 
 type node struct {
-	end   bool
-	child map[rune]*node
+	end     bool
+	word    string
+	variant string
+	child   map[rune]*node
+}
+
+// match 生成该词尾节点在文本区间 [start, end) 上的命中，gaps 为区间内间隔字符的位置
+func (n *node) match(t *filter.Text, start, end int, gaps []int) filter.Match {
+	m := t.Match(start, end)
+	m.Gaps = t.Gaps(gaps)
+	m.Entry = n.word
+	m.Variant = n.variant
+	return m
 }
 
 type NodeFilter struct {
-	root  *node
-	noise *regexp.Regexp
+	root    *node
+	noise   *regexp.Regexp
+	opts    []filter.Option
+	options *filter.Options
+	// owned 写时复制的过滤器已复制的节点，为nil时直接修改节点
+	owned map[*node]bool
+	// mux 保护查找与增删敏感词的并发访问
+	mux sync.RWMutex
 }
 
 func (nf *NodeFilter) IsExistReader(reader io.Reader, excludes ...rune) bool {
@@ -84,19 +103,18 @@ func (nf *NodeFilter) IsExistReader(reader io.Reader, excludes ...rune) bool {
 		if nf.checkExclude(ur, excludes...) {
 			continue
 		}
-		if (unicode.IsSpace(ur) || unicode.IsPunct(ur)) && len(uchars) > 0 {
-			isExist, _ := nf.FindIn(string(uchars[:]))
-			if isExist {
-				return isExist
+		if nf.IsSeparator(ur) && len(uchars) > 0 {
+			if validated, _ := nf.validate(string(uchars)); !validated {
+				return true
 			}
 			uchars = nil
 			continue
 		}
-		uchars = append(uchars, ur)
+		uchars = filter.NormalizeRune(nf.options.Normalizer, uchars, ur)
 	}
 	if len(uchars) > 0 {
-		isExist, _ := nf.FindIn(string(uchars))
-		return isExist
+		validated, _ := nf.validate(string(uchars))
+		return !validated
 	}
 	return false
 }
@@ -107,13 +125,20 @@ func (nf *NodeFilter) IsExist(text string, excludes ...rune) bool {
 	return nf.IsExistReader(buf, excludes...)
 }
 
-func newNodeFilter() *NodeFilter {
+func newNodeFilter(opts ...filter.Option) *NodeFilter {
 	return &NodeFilter{
-		root:  newNode(),
-		noise: regexp.MustCompile(`[\|\s&%$@*]+`),
+		root:    newNode(),
+		noise:   regexp.MustCompile(`[\|\s&%$@*]+`),
+		opts:    opts,
+		options: filter.NewOptions(opts...),
 	}
 }
 
+// Options 获取创建过滤器时使用的可选项
+func (nf *NodeFilter) Options() []filter.Option {
+	return nf.opts
+}
+
 func (nf *NodeFilter) add(texts ...string) {
 	for _, text := range texts {
 		nf.addSensitivewords(text)
@@ -127,38 +152,195 @@ func (nf *NodeFilter) del(texts ...string) {
 }
 
 func (nf *NodeFilter) addSensitivewords(text string) {
-	n := nf.root
-	uchars := []rune(text)
-	for i, l := 0, len(uchars); i < l; i++ {
-		if unicode.IsSpace(uchars[i]) {
+	word := strings.TrimSpace(text)
+	for _, v := range nf.options.Expand(word) {
+		nf.addPath(filter.NormalizeString(nf.options.Normalizer, v.Text), word, v.Kind)
+	}
+}
+
+// addPath 沿 path 添加节点，词尾节点记录对应的敏感词及派生写法类型
+// 派生写法不会覆盖已存在的敏感词
+func (nf *NodeFilter) addPath(path, word, variant string) {
+	n := nf.walk(path, true)
+	if n == nf.root || (n.end && n.variant == "" && variant != "") {
+		return
+	}
+	n.end = true
+	n.word = word
+	n.variant = variant
+}
+
+func (nf *NodeFilter) delSensitivewords(text string) {
+	word := strings.TrimSpace(text)
+	for _, v := range nf.options.Expand(word) {
+		path := filter.NormalizeString(nf.options.Normalizer, v.Text)
+		n := nf.walk(path, false)
+		if n == nil || n == nf.root || !n.end || n.variant != v.Kind || (v.Kind != "" && n.word != word) {
 			continue
 		}
-		if _, ok := n.child[uchars[i]]; !ok {
-			n.child[uchars[i]] = newNode()
+		n.end = false
+		nf.prune(path)
+	}
+}
+
+// prune 自 path 的词尾向上删除不再是词尾且没有子节点的节点，path 上的节点需已由walk复制
+func (nf *NodeFilter) prune(path string) {
+	var (
+		nodes = []*node{nf.root}
+		keys  []rune
+	)
+	for _, r := range path {
+		next, ok := nodes[len(nodes)-1].child[r]
+		if !ok {
+			return
 		}
-		n = n.child[uchars[i]]
+		nodes = append(nodes, next)
+		keys = append(keys, r)
+	}
+	for i := len(keys) - 1; i >= 0; i-- {
+		if n := nodes[i+1]; n.end || len(n.child) > 0 {
+			return
+		}
+		delete(nodes[i].child, keys[i])
 	}
-	n.end = true
 }
 
-func (nf *NodeFilter) delSensitivewords(text string) {
+// walk 查找 path 对应的节点用于修改，create 为true时创建不存在的节点，否则返回nil
+// 写时复制的过滤器会复制路径上与其它过滤器共享的节点
+func (nf *NodeFilter) walk(path string, create bool) *node {
+	nf.root = nf.own(nf.root)
 	n := nf.root
-	uchars := []rune(text)
-	for i, l := 0, len(uchars); i < l; i++ {
-		if next, ok := n.child[uchars[i]]; !ok {
-			return
+	for _, r := range path {
+		next, ok := n.child[r]
+		if !ok {
+			if !create {
+				return nil
+			}
+			next = newNode()
+			if nf.owned != nil {
+				nf.owned[next] = true
+			}
 		} else {
-			n = next
+			next = nf.own(next)
+		}
+		n.child[r] = next
+		n = next
+	}
+	return n
+}
+
+// own 写时复制的过滤器复制与其它过滤器共享的节点，否则返回节点本身
+func (nf *NodeFilter) own(n *node) *node {
+	if nf.owned == nil || nf.owned[n] {
+		return n
+	}
+	c := &node{
+		end:     n.end,
+		word:    n.word,
+		variant: n.variant,
+		child:   make(map[rune]*node, len(n.child)),
+	}
+	for r, next := range n.child {
+		c.child[r] = next
+	}
+	nf.owned[c] = true
+	return c
+}
+
+// Update 实现filter.Updater接口
+// 之后修改原过滤器或新过滤器时都将复制共享的节点，两者互不影响
+func (nf *NodeFilter) Update(added, removed []string) filter.SensitivewordFilter {
+	nf.mux.Lock()
+	nf.owned = make(map[*node]bool)
+	clone := &NodeFilter{
+		root:    nf.root,
+		noise:   nf.noise,
+		opts:    nf.opts,
+		options: nf.options,
+		owned:   make(map[*node]bool),
+	}
+	nf.mux.Unlock()
+	clone.del(removed...)
+	clone.add(added...)
+	return clone
+}
+
+// Compact 实现filter.Compactor接口
+// 按实际大小重建所有节点，之后不再与其它过滤器共享节点
+func (nf *NodeFilter) Compact() {
+	nf.mux.Lock()
+	defer nf.mux.Unlock()
+	if nf.root = compactNode(nf.root); nf.root == nil {
+		nf.root = newNode()
+	}
+	nf.owned = nil
+}
+
+// compactNode 复制以 n 为根的子树，不再通向词尾的子树返回nil
+func compactNode(n *node) *node {
+	c := &node{
+		end:   n.end,
+		child: make(map[rune]*node, len(n.child)),
+	}
+	if n.end {
+		c.word = n.word
+		c.variant = n.variant
+	}
+	for r, next := range n.child {
+		if next = compactNode(next); next != nil {
+			c.child[r] = next
 		}
 	}
-	n.end = false
+	if !c.end && len(c.child) == 0 {
+		return nil
+	}
+	return c
 }
 
+// Stats 实现filter.Compactor接口
+func (nf *NodeFilter) Stats() filter.Stats {
+	nf.mux.RLock()
+	defer nf.mux.RUnlock()
+	var stats filter.Stats
+	var walk func(n *node, depth int)
+	walk = func(n *node, depth int) {
+		stats.Nodes++
+		stats.Bytes += int64(unsafe.Sizeof(*n)) + filter.MapBytes(len(n.child), unsafe.Sizeof(rune(0))+unsafe.Sizeof(n))
+		if n.end {
+			stats.Words++
+			stats.Bytes += int64(len(n.word) + len(n.variant))
+			if depth > stats.Depth {
+				stats.Depth = depth
+			}
+		}
+		for _, next := range n.child {
+			walk(next, depth+1)
+		}
+	}
+	walk(nf.root, 0)
+	return stats
+}
+
+// MaxSpan 实现filter.Spanner接口，派生写法(如拼音)时还需之后的字符判断词尾是否为单词边界
+func (nf *NodeFilter) MaxSpan() int {
+	span := filter.SpanOf(nf.Stats().Depth, nf.options.MaxGap)
+	if len(nf.options.Expanders) > 0 {
+		span += filter.BoundaryContext
+	}
+	return span
+}
+
+// Remove 移除敏感词，可与查找并发调用
 func (nf *NodeFilter) Remove(text ...string) {
+	nf.mux.Lock()
+	defer nf.mux.Unlock()
 	nf.del(text...)
 }
 
+// Add 增加敏感词，可与查找并发调用
 func (nf *NodeFilter) Add(text ...string) {
+	nf.mux.Lock()
+	defer nf.mux.Unlock()
 	nf.add(text...)
 }
 
@@ -203,7 +385,7 @@ func (nf *NodeFilter) FilterReaderResult(reader io.Reader, excludes ...rune) (ma
 		if nf.checkExclude(ur, excludes...) {
 			continue
 		}
-		if (unicode.IsSpace(ur) || unicode.IsPunct(ur)) && len(uchars) > 0 {
+		if nf.IsSeparator(ur) && len(uchars) > 0 {
 			nf.doFilter(uchars[:], data)
 			uchars = nil
 			continue
@@ -217,29 +399,99 @@ func (nf *NodeFilter) FilterReaderResult(reader io.Reader, excludes ...rune) (ma
 }
 
 func (nf *NodeFilter) Replace(text string, delim rune, excludes ...rune) (string, error) {
+	t := filter.NewText(text, filter.NewMatchOptions(filter.WithExcludes(excludes...)), nil, nf.options.Normalizer)
 	uchars := []rune(text)
-	idexs := nf.doIndexes(uchars, excludes...)
-	if len(idexs) == 0 {
-		return "", nil
+	nf.scan(t.Runes, func(start, end int, n *node, gaps []int) bool {
+		n.match(t, start, end, gaps).Mask(uchars, delim)
+		return true
+	})
+	return string(uchars), nil
+}
+
+// ReplaceWith 实现filter.Rewriter接口
+func (nf *NodeFilter) ReplaceWith(text string, r filter.Replacer, opts ...filter.MatchOption) (string, error) {
+	matches, err := nf.FindMatches(text, opts...)
+	if err != nil {
+		return "", err
 	}
-	for i := 0; i < len(idexs); i++ {
-		uchars[idexs[i]] = delim
+	return filter.ReplaceMatches(text, matches, r), nil
+}
+
+// FindMatches 查找文本中出现的所有敏感词及其在原文中的位置
+func (nf *NodeFilter) FindMatches(text string, opts ...filter.MatchOption) ([]filter.Match, error) {
+	o := filter.NewMatchOptions(opts...)
+	t := filter.NewText(text, o, nf.noise, nf.options.Normalizer)
+	var matches []filter.Match
+	nf.scan(t.Runes, func(start, end int, n *node, gaps []int) bool {
+		matches = append(matches, n.match(t, start, end, gaps))
+		return true
+	})
+	return filter.ResolveOverlaps(matches, o.Overlap), nil
+}
+
+// FilterResultWith 实现filter.ResultFilter接口
+func (nf *NodeFilter) FilterResultWith(text string, opts ...filter.MatchOption) (map[string]int, error) {
+	o := filter.NewMatchOptions(opts...)
+	t := filter.NewText(text, o, nf.noise, nf.options.Normalizer)
+	var (
+		matches []filter.Match
+		words   []string
+	)
+	nf.scan(t.Runes, func(start, end int, n *node, gaps []int) bool {
+		matches = append(matches, n.match(t, start, end, gaps))
+		words = append(words, nf.resultWord(t, start, end, gaps, o))
+		return true
+	})
+	data := make(map[string]int)
+	for _, i := range filter.SelectOverlaps(matches, o.Overlap) {
+		data[words[i]]++
 	}
-	return string(uchars), nil
+	return data, nil
 }
 
-// FindIn 检测敏感词
-func (nf *NodeFilter) FindIn(text string, excludes ...rune) (bool, string) {
-	var newWchar []rune
-	uchars := []rune(text)
+// scan 从每个位置开始查找敏感词，以其字符区间 [start, end)、词尾节点及区间内间隔字符的位置回调 fn，
+// fn 返回false时停止查找，gaps 在回调之后会被复用；派生写法的命中需位于单词边界
+func (nf *NodeFilter) scan(uchars []rune, fn func(start, end int, n *node, gaps []int) bool) {
+	var (
+		maxGap = nf.options.MaxGap
+		class  = nf.options.GapClass
+		gaps   []int
+	)
+	nf.mux.RLock()
+	defer nf.mux.RUnlock()
 	for i, l := 0, len(uchars); i < l; i++ {
-		if nf.checkExclude(uchars[i], excludes...) {
-			continue
+		n := nf.root
+		gap := 0
+		gaps = gaps[:0]
+		for j := i; j < l; j++ {
+			next, ok := n.child[uchars[j]]
+			if !ok {
+				if n == nf.root || gap >= maxGap || (class != nil && !class(uchars[j])) {
+					break
+				}
+				gap++
+				gaps = append(gaps, j)
+				continue
+			}
+			gap = 0
+			n = next
+			// 派生写法(如拼音)只按整词匹配，避免误伤正常的英文单词
+			if n.end && (n.variant == "" || filter.IsWholeWord(uchars, i, j+1)) && !fn(i, j+1, n, gaps) {
+				return
+			}
 		}
-		newWchar = append(newWchar, uchars[i])
 	}
-	newText := string(newWchar)
-	validated, first := nf.Validate(newText)
+}
+
+// IsSeparator 实现filter.Segmenter接口，是否为切分文本的分隔字符，允许间隔时不切分文本
+// 按归一化后的字符判断，归一化为字母或数字的标点(如火星文)不切分文本
+func (nf *NodeFilter) IsSeparator(r rune) bool {
+	return nf.options.MaxGap == 0 && filter.IsSeparator(nf.options.Normalizer, r)
+}
+
+// FindIn 检测敏感词
+func (nf *NodeFilter) FindIn(text string, excludes ...rune) (bool, string) {
+	validated, first := nf.Validate(text, excludes...)
 	return !validated, first
 }
 
@@ -251,7 +503,7 @@ func (nf *NodeFilter) Validate(text string, excludes ...rune) (bool, string) {
 		if nf.checkExclude(uchars[i], excludes...) {
 			continue
 		}
-		newWchar = append(newWchar, uchars[i])
+		newWchar = filter.NormalizeRune(nf.options.Normalizer, newWchar, uchars[i])
 	}
 	newText := string(newWchar)
 	return nf.validate(newText)
@@ -260,36 +512,17 @@ func (nf *NodeFilter) Validate(text string, excludes ...rune) (bool, string) {
 // validate 验证字符串是否合法，如不合法则返回false和检测到
 // 的第一个敏感词
 func (nf *NodeFilter) validate(text string) (bool, string) {
-	const (
-		Empty = ""
-	)
 	var (
-		parent  = nf.root
-		current *node
-		runes   = []rune(text)
-		length  = len(runes)
-		left    = 0
-		found   bool
+		runes     = []rune(text)
+		validated = true
+		first     string
 	)
-
-	for position := 0; position < len(runes); position++ {
-		current, found = parent.child[runes[position]]
-
-		if !found || (!current.end && position == length-1) {
-			parent = nf.root
-			position = left
-			left++
-			continue
-		}
-
-		if current.end && left <= position {
-			return false, string(runes[left : position+1])
-		}
-
-		parent = current
-	}
-
-	return true, Empty
+	nf.scan(runes, func(start, end int, _ *node, _ []int) bool {
+		validated = false
+		first = string(runes[start:end])
+		return false
+	})
+	return validated, first
 }
 
 func (nf *NodeFilter) checkExclude(u rune, excludes ...rune) bool {
@@ -306,99 +539,36 @@ func (nf *NodeFilter) checkExclude(u rune, excludes ...rune) bool {
 	return exist
 }
 
+// doFilter 查找一段原文中的敏感词并计数，结果为归一化后除间隔字符以外的文本，SurfaceText 时为原文
 func (nf *NodeFilter) doFilter(uchars []rune, data map[string]int) {
-	var result []string
-	ul := len(uchars)
-	buf := new(bytes.Buffer)
-	n := nf.root
-	for i := 0; i < ul; i++ {
-		if _, ok := n.child[uchars[i]]; !ok {
-			continue
-		}
-		n = n.child[uchars[i]]
-		buf.WriteRune(uchars[i])
-		if n.end {
-			result = append(result, buf.String())
-		}
-		for j := i + 1; j < ul; j++ {
-			if _, ok := n.child[uchars[j]]; !ok {
-				break
-			}
-			n = n.child[uchars[j]]
-			buf.WriteRune(uchars[j])
-			if n.end {
-				result = append(result, buf.String())
-			}
-		}
-		buf.Reset()
-		n = nf.root
-	}
-	for i, l := 0, len(result); i < l; i++ {
-		var c int
-		if v, ok := data[result[i]]; ok {
-			c = v
-		}
-		data[result[i]] = c + 1
-	}
+	t := filter.NewText(string(uchars), filter.NewMatchOptions(), nil, nf.options.Normalizer)
+	nf.scan(t.Runes, func(start, end int, _ *node, gaps []int) bool {
+		data[nf.resultWord(t, start, end, gaps, nil)]++
+		return true
+	})
 }
 
-func (nf *NodeFilter) doIndexes(uchars []rune, excludes ...rune) (idexs []int) {
-	var (
-		tIdexs []int
-		ul     = len(uchars)
-		n      = nf.root
-	)
-	for i := 0; i < ul; i++ {
-		if nf.checkExclude(uchars[i], excludes...) {
-			continue
-		}
-
-		if _, ok := n.child[uchars[i]]; !ok {
-			continue
-		}
-		n = n.child[uchars[i]]
-		tIdexs = append(tIdexs, i)
-		if n.end {
-			idexs = nf.appendTo(idexs, tIdexs)
-			tIdexs = nil
-		}
-		for j := i + 1; j < ul; j++ {
-			if nf.checkExclude(uchars[j], excludes...) {
-				tIdexs = append(tIdexs, j)
-			} else {
-				if _, ok := n.child[uchars[j]]; !ok {
-					break
-				}
-				n = n.child[uchars[j]]
-				tIdexs = append(tIdexs, j)
-				if n.end {
-					idexs = nf.appendTo(idexs, tIdexs)
-				}
-			}
-		}
-		if tIdexs != nil {
-			tIdexs = nil
-		}
-		n = nf.root
+// resultWord 返回命中在结果中的文本，SurfaceText 时为原文中除间隔及 o 中排除的字符以外的文本
+func (nf *NodeFilter) resultWord(t *filter.Text, start, end int, gaps []int, o *filter.MatchOptions) string {
+	if !nf.options.SurfaceText {
+		return wordOf(t.Runes, start, end, gaps)
 	}
-	return
+	m := t.Match(start, end)
+	m.Gaps = t.Gaps(gaps)
+	return m.Surface(o)
 }
 
-func (nf *NodeFilter) appendTo(dst, src []int) []int {
-	var t []int
-	for i, il := 0, len(src); i < il; i++ {
-		var exist bool
-		for j, jl := 0, len(dst); j < jl; j++ {
-			if src[i] == dst[j] {
-				exist = true
-				break
-			}
-		}
-		if !exist {
-			t = append(t, src[i])
+// wordOf 返回区间 [start, end) 内除间隔字符以外的文本
+func wordOf(uchars []rune, start, end int, gaps []int) string {
+	var buf bytes.Buffer
+	for i := start; i < end; i++ {
+		if len(gaps) > 0 && gaps[0] == i {
+			gaps = gaps[1:]
+			continue
 		}
+		buf.WriteRune(uchars[i])
 	}
-	return append(dst, t...)
+	return buf.String()
 }
 
 // UpdateNoisePattern 更新去噪模式
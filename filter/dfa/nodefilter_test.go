@@ -0,0 +1,146 @@
+package dfa
+
+import (
+	"reflect"
+	"testing"
+	"unicode"
+
+	"github.com/hellobchain/sensitivewordfilter/filter"
+	"github.com/hellobchain/sensitivewordfilter/filter/filtertest"
+)
+
+func TestFindMatches(t *testing.T) {
+	nf := NewNodeFilter([]string{"一个", "一个东西", "东西"}).(*NodeFilter)
+
+	testcases := []struct {
+		Text   string
+		Opts   []filter.MatchOption
+		Expect []filter.Match
+	}{
+		{"有一个东西", nil, []filter.Match{
+			{Word: "一个", Entry: "一个", Start: 1, End: 3, ByteStart: 3, ByteEnd: 9},
+			{Word: "一个东西", Entry: "一个东西", Start: 1, End: 5, ByteStart: 3, ByteEnd: 15},
+			{Word: "东西", Entry: "东西", Start: 3, End: 5, ByteStart: 9, ByteEnd: 15},
+		}},
+		{"x东*西", []filter.MatchOption{filter.WithExcludes('*')}, []filter.Match{
+			{Word: "东*西", Entry: "东西", Start: 1, End: 4, ByteStart: 1, ByteEnd: 8},
+		}},
+		{"一 | 个", []filter.MatchOption{filter.WithRemoveNoise()}, []filter.Match{
+			{Word: "一 | 个", Entry: "一个", Start: 0, End: 5, ByteStart: 0, ByteEnd: 9},
+		}},
+		{"一 | 个", nil, nil},
+	}
+
+	for _, tc := range testcases {
+		got, err := nf.FindMatches(tc.Text, tc.Opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(tc.Expect, got) {
+			t.Errorf("find matches %s, got %+v, expect %+v", tc.Text, got, tc.Expect)
+		}
+	}
+}
+
+func TestNormalizer(t *testing.T) {
+	nf := NewNodeFilter([]string{"Bad", "暴力"}, filter.WithNormalizer(filter.DefaultNormalizer())).(*NodeFilter)
+
+	if got, _ := nf.FilterResult("so ＢＡＤ, bäd"); !reflect.DeepEqual(got, map[string]int{"bad": 2}) {
+		t.Errorf("filter result got %v, expect map[bad:2]", got)
+	}
+	if got, _ := nf.Replace("ＢＡＤ暴力!", '*'); got != "*****!" {
+		t.Errorf("replace got %s, expect *****!", got)
+	}
+	if !nf.IsExist("bAd") {
+		t.Errorf("expect bAd to be found")
+	}
+	matches, _ := nf.FindMatches("xＢａｄ")
+	expect := []filter.Match{{Word: "Ｂａｄ", Entry: "Bad", Start: 1, End: 4, ByteStart: 1, ByteEnd: 10}}
+	if !reflect.DeepEqual(matches, expect) {
+		t.Errorf("find matches got %+v, expect %+v", matches, expect)
+	}
+}
+
+func TestMaxGap(t *testing.T) {
+	nf := NewNodeFilter([]string{"暴力"}, filter.WithMaxGap(2, nil)).(*NodeFilter)
+
+	testcases := []struct {
+		Text          string
+		ExpectResult  map[string]int
+		ExpectReplace string
+	}{
+		{"暴x力", map[string]int{"暴力": 1}, "*x*"},
+		{"暴。力!", map[string]int{"暴力": 1}, "*。*!"},
+		{"暴abc力", map[string]int{}, "暴abc力"},
+		{"x暴力", map[string]int{"暴力": 1}, "x**"},
+	}
+
+	for _, tc := range testcases {
+		if got, _ := nf.FilterResult(tc.Text); !reflect.DeepEqual(tc.ExpectResult, got) {
+			t.Errorf("filter result %s, got %v, expect %v", tc.Text, got, tc.ExpectResult)
+		}
+		if got, _ := nf.Replace(tc.Text, '*'); got != tc.ExpectReplace {
+			t.Errorf("replace %s, got %s, expect %s", tc.Text, got, tc.ExpectReplace)
+		}
+	}
+
+	matches, _ := nf.FindMatches("暴..力")
+	expect := []filter.Match{{Word: "暴..力", Entry: "暴力", Start: 0, End: 4, ByteStart: 0, ByteEnd: 8, Gaps: []int{1, 2}}}
+	if !reflect.DeepEqual(expect, matches) {
+		t.Errorf("find matches got %+v, expect %+v", matches, expect)
+	}
+}
+
+func TestMaxGapClass(t *testing.T) {
+	nf := NewNodeFilter([]string{"暴力"}, filter.WithMaxGap(1, unicode.IsPunct)).(*NodeFilter)
+
+	if !nf.IsExist("暴，力") {
+		t.Errorf("expect punct gap to be allowed")
+	}
+	if nf.IsExist("暴x力") {
+		t.Errorf("expect letter gap not to be allowed")
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	old := NewNodeFilter([]string{"暴力", "广告"})
+	updated := old.(filter.Updater).Update([]string{"暴乱"}, []string{"广告"})
+	if !old.IsExist("广告") || old.IsExist("暴乱") {
+		t.Errorf("expect original filter unchanged")
+	}
+	if updated.IsExist("广告") || !updated.IsExist("暴乱") || !updated.IsExist("暴力") {
+		t.Errorf("expect changes applied to updated filter")
+	}
+}
+
+func TestRemovePrune(t *testing.T) {
+	nf := NewNodeFilter([]string{"暴力", "暴力倾向", "广告"})
+	before := nf.(filter.Compactor).Stats()
+	if before.Nodes != 7 || before.Words != 3 {
+		t.Fatalf("unexpected stats %+v", before)
+	}
+	nf.Remove("暴力倾向", "广告")
+	stats := nf.(filter.Compactor).Stats()
+	if stats.Nodes != 3 || stats.Words != 1 || stats.Bytes >= before.Bytes {
+		t.Errorf("expect removed nodes pruned, got %+v", stats)
+	}
+	if !nf.IsExist("暴力") || nf.IsExist("广告") {
+		t.Errorf("expect remaining word found")
+	}
+
+	nf.Remove("暴力")
+	nf.(filter.Compactor).Compact()
+	if stats := nf.(filter.Compactor).Stats(); stats.Nodes != 1 || stats.Words != 0 {
+		t.Errorf("expect only root left, got %+v", stats)
+	}
+	nf.Add("广告")
+	if !nf.IsExist("广告") {
+		t.Errorf("expect word added after compact")
+	}
+}
+
+func TestConformance(t *testing.T) {
+	filtertest.Run(t, func(words []string) filter.SensitivewordFilter {
+		return NewNodeFilter(words)
+	})
+}
@@ -0,0 +1,79 @@
+package dfa
+
+import (
+	"context"
+	"io"
+
+	"github.com/hellobchain/sensitivewordfilter/filter"
+)
+
+// partial 流式匹配中尚未结束的匹配
+type partial struct {
+	n     *node
+	start int
+	gap   int
+	gaps  []int
+	// wordStart 起始字符是否满足整词匹配的开头
+	wordStart bool
+}
+
+// FilterStream 实现filter.Streamer接口
+// 命中派生写法(如拼音)时需再读取两个字符才能判断词尾是否为单词边界，回调会相应推迟
+func (nf *NodeFilter) FilterStream(ctx context.Context, reader io.Reader, fn func(filter.Match) bool) error {
+	var (
+		s      = filter.NewStream(ctx, reader, filter.NewMatchOptions(), nf.options.Normalizer)
+		maxGap = nf.options.MaxGap
+		class  = nf.options.GapClass
+		active []partial
+		next   []partial
+	)
+	for {
+		r, i, err := s.Next()
+		if err == io.EOF {
+			s.Flush(true, fn)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		next = next[:0]
+		nf.mux.RLock()
+		active = append(active, partial{n: nf.root, start: i, wordStart: s.WordStart()})
+		for _, p := range active {
+			child, ok := p.n.child[r]
+			if !ok {
+				if p.n == nf.root {
+					continue
+				}
+				if p.gap >= maxGap || (class != nil && !class(r)) {
+					continue
+				}
+				p.gap++
+				p.gaps = append(p.gaps[:len(p.gaps):len(p.gaps)], i)
+				next = append(next, p)
+				continue
+			}
+			p.n, p.gap = child, 0
+			// 派生写法的词首及词尾需为单词边界
+			if whole := child.variant != ""; child.end && (!whole || p.wordStart) {
+				m := s.Match(p.start, i+1, p.gaps)
+				m.Entry = child.word
+				m.Variant = child.variant
+				s.Push(m, whole)
+			}
+			if len(child.child) > 0 {
+				next = append(next, p)
+			}
+		}
+		nf.mux.RUnlock()
+		if !s.Flush(false, fn) {
+			return nil
+		}
+		active, next = next, active
+		release := i + 1
+		if len(active) > 0 {
+			release = active[0].start
+		}
+		s.Release(release)
+	}
+}
@@ -5,6 +5,15 @@ import (
 )
 
 // SensitivewordFilter 提供敏感词过滤接口
+//
+// 所有实现遵循相同的匹配规则，可使用 filter/filtertest 检查：
+//  1. 增删敏感词时去除首尾的空白，中间的空白作为敏感词的一部分参与匹配
+//  2. 敏感词与待检测文本在匹配前经过相同的归一化处理
+//  3. excludes 中的字符不参与匹配，Replace 保留命中以外的排除字符，命中区间内的与命中一起替换
+//  4. 查找前不会去除噪音，需要时先调用 RemoveNoise 或使用 FindMatches 的 WithRemoveNoise
+//  5. 报告所有命中，包括重叠及被包含的命中，支持查找选项的方法可通过 WithOverlap 选择重叠的处理方式
+//  6. 未开启间隔匹配时，Filter、FilterResult、FilterReader、FilterReaderResult、IsExist 及 IsExistReader
+//     在空白及标点处切分文本，敏感词不会跨越空白及标点命中；Replace、Validate 及 FindMatches 不切分文本
 type SensitivewordFilter interface {
 
 	// Add 增加文本
@@ -37,7 +46,7 @@ type SensitivewordFilter interface {
 	// 如果出现异常，则返回error
 	FilterReaderResult(reader io.Reader, excludes ...rune) (map[string]int, error)
 
-	// Replace 使用字符替换文本中的敏感词
+	// Replace 使用字符替换文本中的敏感词，没有命中时返回原文
 	// delim 替换的字符
 	// 如果出现异常，则返回error
 	Replace(text string, delim rune, excludes ...rune) (string, error)
@@ -47,3 +56,10 @@ type SensitivewordFilter interface {
 
 	IsExistReader(reader io.Reader, excludes ...rune) bool
 }
+
+// Updater 支持增量更新的过滤器
+type Updater interface {
+	// Update 返回增加 added 并移除 removed 后的新过滤器，原过滤器保持不变
+	// 新过滤器与原过滤器共享未修改的节点(写时复制)，原过滤器可继续并发读取，但不应再修改
+	Update(added, removed []string) SensitivewordFilter
+}
@@ -0,0 +1,305 @@
+// Package filtertest 提供敏感词过滤器的一致性测试，
+// 任何 filter.SensitivewordFilter 的实现都可以通过 Run 检查是否符合 filter 包约定的匹配规则
+package filtertest
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hellobchain/sensitivewordfilter/filter"
+)
+
+// Words 一致性测试使用的敏感词
+var Words = []string{"暴力", "一个", "一个东西", "东西", "个东", "hello world", "东西五", "ab", "abcd", "cde"}
+
+// Validator 支持检测文本是否合法的过滤器
+type Validator interface {
+	// Validate 检测文本是否合法，不合法时返回false和查找到的第一个敏感词
+	Validate(text string, excludes ...rune) (bool, string)
+	// FindIn 检测文本中是否存在敏感词，存在时返回true和查找到的第一个敏感词
+	FindIn(text string, excludes ...rune) (bool, string)
+}
+
+// Run 运行一致性测试，newFilter 使用给定的敏感词创建新的过滤器
+func Run(t *testing.T, newFilter func(words []string) filter.SensitivewordFilter) {
+	t.Run("Replace", func(t *testing.T) { testReplace(t, newFilter(Words)) })
+	t.Run("FilterResult", func(t *testing.T) { testFilterResult(t, newFilter(Words)) })
+	t.Run("Filter", func(t *testing.T) { testFilter(t, newFilter(Words)) })
+	t.Run("IsExist", func(t *testing.T) { testIsExist(t, newFilter(Words)) })
+	t.Run("Validate", func(t *testing.T) { testValidate(t, newFilter(Words)) })
+	t.Run("AddRemove", func(t *testing.T) { testAddRemove(t, newFilter(nil)) })
+	t.Run("FindMatches", func(t *testing.T) { testFindMatches(t, newFilter(Words)) })
+	t.Run("ReplaceWith", func(t *testing.T) { testReplaceWith(t, newFilter(Words)) })
+	t.Run("Overlap", func(t *testing.T) { testOverlap(t, newFilter(Words)) })
+}
+
+func testReplace(t *testing.T, f filter.SensitivewordFilter) {
+	testcases := []struct {
+		Text     string
+		Excludes []rune
+		Expect   string
+	}{
+		// 没有命中时返回原文
+		{"", nil, ""},
+		{"没有命中", nil, "没有命中"},
+		// 重叠的命中都被替换
+		{"我有一个东西", nil, "我有****"},
+		// 首尾相接的重叠命中都被替换，与是否指定排除字符无关
+		{"一个东西五", nil, "*****"},
+		{"一个东西五", []rune{'x'}, "*****"},
+		{"abcde", nil, "*****"},
+		// 文本不切分，空白及标点保持不变
+		{"暴力，暴 力", nil, "**，暴 力"},
+		// 敏感词中间的空白同样参与匹配
+		{"say hello world!", nil, "say ***********!"},
+		{"helloworld", nil, "helloworld"},
+		// 排除的字符不参与匹配，命中以外的保持不变，命中区间内的一起替换
+		{"x暴x力x", []rune{'x'}, "x***x"},
+		{"暴|力", nil, "暴|力"},
+	}
+	for _, tc := range testcases {
+		got, err := f.Replace(tc.Text, '*', tc.Excludes...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.Expect {
+			t.Errorf("Replace(%q, %q) got %q, expect %q", tc.Text, tc.Excludes, got, tc.Expect)
+		}
+	}
+}
+
+func testFilterResult(t *testing.T, f filter.SensitivewordFilter) {
+	testcases := []struct {
+		Text     string
+		Excludes []rune
+		Expect   map[string]int
+	}{
+		{"没有命中", nil, map[string]int{}},
+		// 每个命中都计数，包括重叠及被包含的命中
+		{"我有一个东西", nil, map[string]int{"一个": 1, "一个东西": 1, "个东": 1, "东西": 1}},
+		{"暴力暴力，暴力", nil, map[string]int{"暴力": 3}},
+		// 敏感词不会跨越空白及标点命中，包括本身含有空白的敏感词
+		{"暴，力", nil, map[string]int{}},
+		{"say hello world", nil, map[string]int{}},
+		// 结果为去除排除字符后的命中文本
+		{"暴*力", []rune{'*'}, map[string]int{"暴力": 1}},
+	}
+	for _, tc := range testcases {
+		got, err := f.FilterResult(tc.Text, tc.Excludes...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) == 0 && len(tc.Expect) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.Expect) {
+			t.Errorf("FilterResult(%q, %q) got %v, expect %v", tc.Text, tc.Excludes, got, tc.Expect)
+		}
+		reader, err := f.FilterReaderResult(strings.NewReader(tc.Text), tc.Excludes...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(reader, got) {
+			t.Errorf("FilterReaderResult(%q) got %v, expect same as FilterResult %v", tc.Text, reader, got)
+		}
+	}
+}
+
+func testFilter(t *testing.T, f filter.SensitivewordFilter) {
+	got, err := f.Filter("没有命中")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != nil {
+		t.Errorf("Filter got %q, expect nil", got)
+	}
+	for _, read := range []func(string) ([]string, error){
+		func(text string) ([]string, error) { return f.Filter(text) },
+		func(text string) ([]string, error) { return f.FilterReader(strings.NewReader(text)) },
+	} {
+		got, err := read("暴力，一个，暴力")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(got)
+		if expect := []string{"一个", "暴力"}; !reflect.DeepEqual(got, expect) {
+			t.Errorf("Filter got %q, expect %q", got, expect)
+		}
+	}
+}
+
+func testIsExist(t *testing.T, f filter.SensitivewordFilter) {
+	testcases := []struct {
+		Text     string
+		Excludes []rune
+		Expect   bool
+	}{
+		{"", nil, false},
+		{"没有命中", nil, false},
+		{"这是暴力", nil, true},
+		{"暴，力", nil, false},
+		{"say hello world", nil, false},
+		{"暴|力", nil, false},
+		{"暴|力", []rune{'|'}, true},
+	}
+	for _, tc := range testcases {
+		if got := f.IsExist(tc.Text, tc.Excludes...); got != tc.Expect {
+			t.Errorf("IsExist(%q, %q) got %v, expect %v", tc.Text, tc.Excludes, got, tc.Expect)
+		}
+		if got := f.IsExistReader(strings.NewReader(tc.Text), tc.Excludes...); got != tc.Expect {
+			t.Errorf("IsExistReader(%q, %q) got %v, expect %v", tc.Text, tc.Excludes, got, tc.Expect)
+		}
+	}
+}
+
+func testValidate(t *testing.T, f filter.SensitivewordFilter) {
+	v, ok := f.(Validator)
+	if !ok {
+		t.Skip("filter does not implement Validator")
+	}
+	testcases := []struct {
+		Text        string
+		Excludes    []rune
+		ExpectFirst string
+	}{
+		{"没有命中", nil, ""},
+		// 第一个敏感词为起始位置最靠前的命中中最短的
+		{"我有一个东西", nil, "一个"},
+		{"两个东西", nil, "个东"},
+		// 不会隐式去除噪音
+		{"暴|力", nil, ""},
+		{"暴|力", []rune{'|'}, "暴力"},
+	}
+	for _, tc := range testcases {
+		pass, first := v.Validate(tc.Text, tc.Excludes...)
+		if pass != (tc.ExpectFirst == "") || first != tc.ExpectFirst {
+			t.Errorf("Validate(%q, %q) got %v %q, expect first %q", tc.Text, tc.Excludes, pass, first, tc.ExpectFirst)
+		}
+		found, first := v.FindIn(tc.Text, tc.Excludes...)
+		if found != (tc.ExpectFirst != "") || first != tc.ExpectFirst {
+			t.Errorf("FindIn(%q, %q) got %v %q, expect first %q", tc.Text, tc.Excludes, found, first, tc.ExpectFirst)
+		}
+	}
+}
+
+func testAddRemove(t *testing.T, f filter.SensitivewordFilter) {
+	if f.IsExist("暴力") {
+		t.Fatalf("expect empty filter")
+	}
+	// 首尾空白被去除
+	f.Add(" 暴力 ", "广告")
+	if !f.IsExist("暴力") || !f.IsExist("广告") {
+		t.Errorf("expect added words found")
+	}
+	f.Remove("暴力")
+	if f.IsExist("暴力") {
+		t.Errorf("expect removed word not found")
+	}
+	if !f.IsExist("广告") {
+		t.Errorf("expect other words unaffected by remove")
+	}
+	f.Remove("不存在")
+	if got, _ := f.Replace("暴力广告", '*'); got != "暴力**" {
+		t.Errorf("Replace got %q, expect %q", got, "暴力**")
+	}
+}
+
+func testFindMatches(t *testing.T, f filter.SensitivewordFilter) {
+	m, ok := f.(filter.Matcher)
+	if !ok {
+		t.Skip("filter does not implement filter.Matcher")
+	}
+	matches, err := m.FindMatches("x一个东西")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 只比较约定的字段，Rule 等字段由各实现自行设置
+	got := make([]filter.Match, len(matches))
+	for i, m := range matches {
+		got[i] = filter.Match{Word: m.Word, Entry: m.Entry, Start: m.Start, End: m.End, ByteStart: m.ByteStart, ByteEnd: m.ByteEnd}
+	}
+	expect := []filter.Match{
+		{Word: "一个", Entry: "一个", Start: 1, End: 3, ByteStart: 1, ByteEnd: 7},
+		{Word: "一个东西", Entry: "一个东西", Start: 1, End: 5, ByteStart: 1, ByteEnd: 13},
+		{Word: "个东", Entry: "个东", Start: 2, End: 4, ByteStart: 4, ByteEnd: 10},
+		{Word: "东西", Entry: "东西", Start: 3, End: 5, ByteStart: 7, ByteEnd: 13},
+	}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("FindMatches got %+v, expect %+v", got, expect)
+	}
+	if got, _ := m.FindMatches("没有命中"); got != nil {
+		t.Errorf("FindMatches got %+v, expect nil", got)
+	}
+}
+
+func testReplaceWith(t *testing.T, f filter.SensitivewordFilter) {
+	r, ok := f.(filter.Rewriter)
+	if !ok {
+		t.Skip("filter does not implement filter.Rewriter")
+	}
+	for _, text := range []string{"没有命中", "暴力，hello world", "x一个东西", "一个东西五", "abcde"} {
+		got, err := r.ReplaceWith(text, filter.MaskReplacer('*'))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expect, _ := f.Replace(text, '*'); got != expect {
+			t.Errorf("ReplaceWith(%q) got %q, expect same as Replace %q", text, got, expect)
+		}
+	}
+}
+
+func testOverlap(t *testing.T, f filter.SensitivewordFilter) {
+	m, ok := f.(filter.Matcher)
+	if !ok {
+		t.Skip("filter does not implement filter.Matcher")
+	}
+	const text = "x一个东西"
+	// 重叠的命中无法同时替换，OverlapAll 时按 OverlapLeftmostLongest 替换
+	testcases := []struct {
+		Overlap       filter.Overlap
+		Expect        []string
+		ExpectReplace string
+	}{
+		{filter.OverlapAll, []string{"一个", "一个东西", "个东", "东西"}, "x#"},
+		{filter.OverlapLeftmostLongest, []string{"一个东西"}, "x#"},
+		{filter.OverlapLeftmostFirst, []string{"一个", "东西"}, "x##"},
+		{filter.OverlapGreedy, []string{"一个东西"}, "x#"},
+	}
+	for _, tc := range testcases {
+		matches, err := m.FindMatches(text, filter.WithOverlap(tc.Overlap))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, m := range matches {
+			got = append(got, m.Entry)
+		}
+		if !reflect.DeepEqual(got, tc.Expect) {
+			t.Errorf("FindMatches overlap %d got %q, expect %q", tc.Overlap, got, tc.Expect)
+		}
+		if r, ok := f.(filter.Rewriter); ok {
+			replaced, err := r.ReplaceWith(text, filter.FixedReplacer("#"), filter.WithOverlap(tc.Overlap))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if replaced != tc.ExpectReplace {
+				t.Errorf("ReplaceWith overlap %d got %q, expect %q", tc.Overlap, replaced, tc.ExpectReplace)
+			}
+		}
+		if rf, ok := f.(filter.ResultFilter); ok {
+			result, err := rf.FilterResultWith(text, filter.WithOverlap(tc.Overlap))
+			if err != nil {
+				t.Fatal(err)
+			}
+			expect := make(map[string]int)
+			for _, word := range tc.Expect {
+				expect[word]++
+			}
+			if !reflect.DeepEqual(result, expect) {
+				t.Errorf("FilterResultWith overlap %d got %v, expect %v", tc.Overlap, result, expect)
+			}
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package filter
+
+import (
+	"regexp"
+	"sort"
+	"unicode/utf8"
+)
+
+// Match 文本中的一次敏感词命中
+type Match struct {
+	// Word 原文中命中的文本
+	Word string
+	// Entry 命中的字典词条
+	Entry string
+	// Variant 命中的派生写法类型(如拼音)，直接命中字典词条时为空字符串
+	Variant string
+	// Rule 命中的规则原文，仅规则过滤器(filter/rule)设置
+	Rule string
+	// Start, End 命中文本在原文中的字符(rune)区间 [Start, End)
+	Start int
+	End   int
+	// ByteStart, ByteEnd 命中文本在原文中的字节区间 [ByteStart, ByteEnd)
+	ByteStart int
+	ByteEnd   int
+	// Meta 命中词条的分类、严重程度及处理方式，未设置时为零值
+	Meta Meta
+	// Gaps 命中区间内作为间隔被跳过的字符在原文中的字符下标，没有间隔时为nil
+	Gaps []int
+}
+
+// Surface 返回原文中命中的除间隔字符及 o 中排除的字符以外的文本，o 为nil时不排除字符
+func (m Match) Surface(o *MatchOptions) string {
+	var (
+		runes []rune
+		gaps  = m.Gaps
+	)
+	i := m.Start
+	for _, r := range m.Word {
+		switch {
+		case len(gaps) > 0 && gaps[0] == i:
+			gaps = gaps[1:]
+		case o == nil || !o.IsExclude(r):
+			runes = append(runes, r)
+		}
+		i++
+	}
+	return string(runes)
+}
+
+// Mask 使用 delim 替换原文字符 uchars 中命中的敏感词，间隔字符保持不变
+func (m Match) Mask(uchars []rune, delim rune) {
+	gaps := m.Gaps
+	for i := m.Start; i < m.End; i++ {
+		if len(gaps) > 0 && gaps[0] == i {
+			gaps = gaps[1:]
+			continue
+		}
+		uchars[i] = delim
+	}
+}
+
+// Matcher 提供带位置信息的敏感词查找接口
+type Matcher interface {
+	// FindMatches 查找文本中出现的所有敏感词，按出现位置排序返回
+	// 如果敏感词不存在则返回nil，如果出现异常，则返回error
+	FindMatches(text string, opts ...MatchOption) ([]Match, error)
+}
+
+// SortMatches 按命中位置排序，起始位置相同时较短的在前
+func SortMatches(matches []Match) {
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Start != matches[j].Start {
+			return matches[i].Start < matches[j].Start
+		}
+		return matches[i].End < matches[j].End
+	})
+}
+
+// MatchOption 查找敏感词时的可选项
+type MatchOption func(*MatchOptions)
+
+// MatchOptions 查找敏感词时的配置
+type MatchOptions struct {
+	// Excludes 匹配时跳过的字符
+	Excludes []rune
+	// RemoveNoise 匹配时是否跳过过滤器去噪模式匹配到的字符
+	RemoveNoise bool
+	// Overlap 命中区间重叠时的处理方式
+	Overlap Overlap
+}
+
+// WithExcludes 匹配时跳过指定的字符
+func WithExcludes(excludes ...rune) MatchOption {
+	return func(o *MatchOptions) {
+		o.Excludes = append(o.Excludes, excludes...)
+	}
+}
+
+// WithRemoveNoise 匹配时跳过过滤器去噪模式(RemoveNoise)匹配到的字符
+func WithRemoveNoise() MatchOption {
+	return func(o *MatchOptions) {
+		o.RemoveNoise = true
+	}
+}
+
+// NewMatchOptions 根据可选项生成配置
+func NewMatchOptions(opts ...MatchOption) *MatchOptions {
+	o := new(MatchOptions)
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// IsExclude 字符是否需要排除
+func (o *MatchOptions) IsExclude(r rune) bool {
+	for i, l := 0, len(o.Excludes); i < l; i++ {
+		if r == o.Excludes[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// Text 参与匹配的文本，记录了每个参与匹配的字符在原文中的位置，
+// 以便将命中区间映射回原文
+// 经过归一化后一个原文字符可能对应零个或多个参与匹配的字符
+type Text struct {
+	source string
+	// Runes 参与匹配的字符
+	Runes []rune
+	// index Runes[i] 在原文中的字符下标
+	index []int
+	// offsets 原文第 i 个字符的字节偏移，最后一个元素为原文长度
+	offsets []int
+}
+
+// NewText 按匹配配置预处理文本，跳过需要排除的字符以及去噪模式 noise 匹配到的字符，
+// 其余字符经过归一化 n 后参与匹配
+func NewText(text string, o *MatchOptions, noise *regexp.Regexp, n Normalizer) *Text {
+	var noises [][]int
+	if o.RemoveNoise && noise != nil {
+		noises = noise.FindAllStringIndex(text, -1)
+	}
+	count := utf8.RuneCountInString(text)
+	t := &Text{
+		source:  text,
+		Runes:   make([]rune, 0, count),
+		index:   make([]int, 0, count),
+		offsets: make([]int, 0, count+1),
+	}
+	i := 0
+	for offset, r := range text {
+		t.offsets = append(t.offsets, offset)
+		for len(noises) > 0 && noises[0][1] <= offset {
+			noises = noises[1:]
+		}
+		isNoise := len(noises) > 0 && noises[0][0] <= offset
+		if !isNoise && !o.IsExclude(r) {
+			t.Runes = NormalizeRune(n, t.Runes, r)
+			for len(t.index) < len(t.Runes) {
+				t.index = append(t.index, i)
+			}
+		}
+		i++
+	}
+	t.offsets = append(t.offsets, len(text))
+	return t
+}
+
+// Gaps 将参与匹配字符中作为间隔的位置映射为原文中的字符下标
+func (t *Text) Gaps(positions []int) []int {
+	if len(positions) == 0 {
+		return nil
+	}
+	gaps := make([]int, 0, len(positions))
+	for _, p := range positions {
+		if i := t.index[p]; len(gaps) == 0 || gaps[len(gaps)-1] != i {
+			gaps = append(gaps, i)
+		}
+	}
+	return gaps
+}
+
+// Match 将参与匹配字符的区间 [start, end) 映射为原文中的命中，命中的词条信息由调用方填充
+func (t *Text) Match(start, end int) Match {
+	m := Match{
+		Start: t.index[start],
+		End:   t.index[end-1] + 1,
+	}
+	m.ByteStart = t.offsets[m.Start]
+	m.ByteEnd = t.offsets[m.End]
+	m.Word = t.source[m.ByteStart:m.ByteEnd]
+	return m
+}
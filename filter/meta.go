@@ -0,0 +1,151 @@
+package filter
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// Meta 敏感词词条的附加信息
+type Meta struct {
+	// Category 分类，如 politics、porn、ads、abuse
+	Category string
+	// Severity 严重程度，0表示未设置
+	Severity int
+	// Action 命中后的处理方式，如 block、review、mask
+	Action string
+}
+
+// NewMetaFilter 使用词条附加信息包装敏感词过滤器
+// metas 以字典词条为键，FindMatches 返回的命中将携带对应词条的附加信息
+func NewMetaFilter(f MatchFilter, metas map[string]Meta) *MetaFilter {
+	mf := &MetaFilter{
+		filter: f,
+		metas:  make(map[string]Meta, len(metas)),
+	}
+	for k, v := range metas {
+		mf.metas[k] = v
+	}
+	return mf
+}
+
+// MetaFilter 命中结果携带词条附加信息的敏感词过滤器
+type MetaFilter struct {
+	filter MatchFilter
+	mux    sync.RWMutex
+	metas  map[string]Meta
+}
+
+// Unwrap 获取被包装的敏感词过滤器
+func (mf *MetaFilter) Unwrap() MatchFilter {
+	return mf.filter
+}
+
+// MarshalBinary 实现encoding.BinaryMarshaler接口，将被包装的过滤器及词条的附加信息编码为二进制词典
+// 被包装的过滤器需实现DictWriter，否则返回 ErrDictUnsupported
+func (mf *MetaFilter) MarshalBinary() ([]byte, error) {
+	w, ok := mf.filter.(DictWriter)
+	if !ok {
+		return nil, ErrDictUnsupported
+	}
+	e := w.EncodeDict()
+	mf.mux.RLock()
+	e.Metas(mf.metas)
+	mf.mux.RUnlock()
+	return e.Bytes(), nil
+}
+
+// AddWithMeta 增加敏感词及其附加信息
+func (mf *MetaFilter) AddWithMeta(word string, meta Meta) {
+	mf.mux.Lock()
+	mf.metas[word] = meta
+	mf.mux.Unlock()
+	mf.filter.Add(word)
+}
+
+// Meta 获取敏感词的附加信息
+func (mf *MetaFilter) Meta(word string) (Meta, bool) {
+	mf.mux.RLock()
+	meta, ok := mf.metas[word]
+	mf.mux.RUnlock()
+	return meta, ok
+}
+
+func (mf *MetaFilter) Add(text ...string) {
+	mf.filter.Add(text...)
+}
+
+// Remove 移除敏感词及其附加信息
+func (mf *MetaFilter) Remove(text ...string) {
+	mf.mux.Lock()
+	for _, word := range text {
+		delete(mf.metas, word)
+	}
+	mf.mux.Unlock()
+	mf.filter.Remove(text...)
+}
+
+// FindMatches 查找文本中出现的所有敏感词，并设置命中词条的附加信息
+// 设置附加信息后再处理区间重叠的命中，严重程度相同的命中才按其它规则选择
+func (mf *MetaFilter) FindMatches(text string, opts ...MatchOption) ([]Match, error) {
+	matches, err := mf.filter.FindMatches(text, append(opts[:len(opts):len(opts)], WithOverlap(OverlapAll))...)
+	if err != nil {
+		return nil, err
+	}
+	mf.mux.RLock()
+	for i := range matches {
+		matches[i].Meta = mf.metas[matches[i].Entry]
+	}
+	mf.mux.RUnlock()
+	return ResolveOverlaps(matches, NewMatchOptions(opts...).Overlap), nil
+}
+
+// FilterStream 流式查找敏感词，并设置命中词条的附加信息
+// 被包装的过滤器未实现Streamer接口时返回ErrStreamUnsupported
+func (mf *MetaFilter) FilterStream(ctx context.Context, reader io.Reader, fn func(Match) bool) error {
+	s, ok := mf.filter.(Streamer)
+	if !ok {
+		return ErrStreamUnsupported
+	}
+	return s.FilterStream(ctx, reader, func(m Match) bool {
+		m.Meta, _ = mf.Meta(m.Entry)
+		return fn(m)
+	})
+}
+
+func (mf *MetaFilter) Filter(text string, excludes ...rune) ([]string, error) {
+	return mf.filter.Filter(text, excludes...)
+}
+
+func (mf *MetaFilter) FilterResult(text string, excludes ...rune) (map[string]int, error) {
+	return mf.filter.FilterResult(text, excludes...)
+}
+
+func (mf *MetaFilter) FilterReader(reader io.Reader, excludes ...rune) ([]string, error) {
+	return mf.filter.FilterReader(reader, excludes...)
+}
+
+func (mf *MetaFilter) FilterReaderResult(reader io.Reader, excludes ...rune) (map[string]int, error) {
+	return mf.filter.FilterReaderResult(reader, excludes...)
+}
+
+func (mf *MetaFilter) Replace(text string, delim rune, excludes ...rune) (string, error) {
+	return mf.filter.Replace(text, delim, excludes...)
+}
+
+// ReplaceWith 实现Rewriter接口，命中携带词条的附加信息，可按分类替换
+func (mf *MetaFilter) ReplaceWith(text string, r Replacer, opts ...MatchOption) (string, error) {
+	matches, err := mf.FindMatches(text, opts...)
+	if err != nil {
+		return "", err
+	}
+	return ReplaceMatches(text, matches, r), nil
+}
+
+func (mf *MetaFilter) IsExist(text string, excludes ...rune) bool {
+	return mf.filter.IsExist(text, excludes...)
+}
+
+func (mf *MetaFilter) IsExistReader(reader io.Reader, excludes ...rune) bool {
+	return mf.filter.IsExistReader(reader, excludes...)
+}
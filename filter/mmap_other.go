@@ -0,0 +1,11 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package filter
+
+import "io/ioutil"
+
+// MapFile 读取整个文件，支持内存映射的平台以只读方式映射文件
+func MapFile(path string) ([]byte, error) {
+	return ioutil.ReadFile(path)
+}
@@ -0,0 +1,290 @@
+package common
+
+import (
+	"sort"
+	"unsafe"
+
+	wordfilter "github.com/hellobchain/sensitivewordfilter/filter"
+)
+
+// MarshalBinary 实现encoding.BinaryMarshaler接口
+// 将所有节点(包括词尾节点对应的敏感词及派生写法类型)编码为带版本号及校验和的二进制词典
+func (tree *Trie) MarshalBinary() ([]byte, error) {
+	e := wordfilter.NewDictEncoder(0)
+	tree.encodeNodes(e)
+	return e.Bytes(), nil
+}
+
+// encodeNodes 按先序写入所有节点
+func (tree *Trie) encodeNodes(e *wordfilter.DictEncoder) {
+	var encode func(node *Node)
+	encode = func(node *Node) {
+		e.Node(wordfilter.DictNode{
+			Rune:     node.Character,
+			End:      node.isPathEnd,
+			Word:     node.word,
+			Variant:  node.variant,
+			Children: len(node.Children),
+		})
+		runes := make([]rune, 0, len(node.Children))
+		for r := range node.Children {
+			runes = append(runes, r)
+		}
+		sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+		for _, r := range runes {
+			encode(node.Children[r])
+		}
+	}
+	encode(tree.Root)
+}
+
+// UnmarshalBinary 实现encoding.BinaryUnmarshaler接口，以二进制词典中的节点替换Trie的所有节点
+// 词典无效时返回error，Trie保持不变
+func (tree *Trie) UnmarshalBinary(data []byte) error {
+	d, err := wordfilter.NewDictDecoder(data)
+	if err != nil {
+		return err
+	}
+	return tree.decodeNodes(d)
+}
+
+// decodeNodes 以词典中的节点替换所有节点，词典无效时返回error，Trie保持不变
+func (tree *Trie) decodeNodes(d *wordfilter.DictDecoder) error {
+	var decode func(root bool) (*Node, error)
+	decode = func(root bool) (*Node, error) {
+		dn, err := d.Node()
+		if err != nil {
+			return nil, err
+		}
+		node := &Node{
+			isRootNode: root,
+			isPathEnd:  dn.End,
+			word:       dn.Word,
+			variant:    dn.Variant,
+			Character:  dn.Rune,
+			Children:   make(map[rune]*Node, dn.Children),
+		}
+		for i := 0; i < dn.Children; i++ {
+			next, err := decode(false)
+			if err != nil {
+				return nil, err
+			}
+			node.Children[next.Character] = next
+		}
+		return node, nil
+	}
+	root, err := decode(true)
+	if err != nil {
+		return err
+	}
+	if err := d.Done(); err != nil {
+		return err
+	}
+	tree.Root = root
+	tree.owned = nil
+	return nil
+}
+
+// MarshalBinary 实现encoding.BinaryMarshaler接口，与相同内容的Trie生成的二进制词典相同
+func (da *DoubleArray) MarshalBinary() ([]byte, error) {
+	e := wordfilter.NewDictEncoder(0)
+	da.encodeNodes(e)
+	return e.Bytes(), nil
+}
+
+// encodeNodes 按先序写入所有节点
+func (da *DoubleArray) encodeNodes(e *wordfilter.DictEncoder) {
+	da.walk(func(s int32, path []rune, children int) {
+		n := wordfilter.DictNode{Children: children}
+		if len(path) > 0 {
+			n.Rune = path[len(path)-1]
+		}
+		if v := da.value[s]; v != 0 {
+			n.End = true
+			n.Word = da.entries[v-1].word
+			n.Variant = da.entries[v-1].variant
+		}
+		e.Node(n)
+	})
+}
+
+// UnmarshalBinary 实现encoding.BinaryUnmarshaler接口，以二进制词典中的词替换所有词
+// 词典无效时返回error，双数组Trie保持不变
+func (da *DoubleArray) UnmarshalBinary(data []byte) error {
+	d, err := wordfilter.NewDictDecoder(data)
+	if err != nil {
+		return err
+	}
+	return da.decodeNodes(d)
+}
+
+// decodeNodes 以词典中的节点替换所有词，优先使用词典中双数组的映像，词典无效时返回error，双数组Trie保持不变
+func (da *DoubleArray) decodeNodes(d *wordfilter.DictDecoder) error {
+	if d.Image() != nil {
+		c, err := loadImage(d)
+		if err != nil {
+			return err
+		}
+		*da = *c
+		return nil
+	}
+	c := NewDoubleArray()
+	var path []rune
+	var decode func(root bool) error
+	decode = func(root bool) error {
+		dn, err := d.Node()
+		if err != nil {
+			return err
+		}
+		if !root {
+			path = append(path, dn.Rune)
+			defer func() { path = path[:len(path)-1] }()
+		}
+		if dn.End && !root {
+			c.AddEntry(string(path), dn.Word, dn.Variant)
+		}
+		for i := 0; i < dn.Children; i++ {
+			if err := decode(false); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := decode(true); err != nil {
+		return err
+	}
+	if err := d.Done(); err != nil {
+		return err
+	}
+	*da = *c
+	return nil
+}
+
+// 双数组映像的格式(均为uint32)：
+//
+//	len(check) len(low) len(runes) len(high) len(entries) len(unused)
+//	base check value first sibling   各 len(check) 个
+//	low runes                        各 len(low)、len(runes) 个
+//	high                             len(high) 对字符及其编码，按字符排序
+//	entries                          len(entries) 对敏感词及派生写法类型在字符串表中的下标
+//	unused                           len(unused) 个
+const daImageHeader = 6
+
+// encodeImage 写入双数组的映像，加载时数组可直接引用词典的数据
+func (da *DoubleArray) encodeImage(e *wordfilter.DictEncoder) {
+	n := len(da.check)
+	image := make([]uint32, 0, daImageHeader+5*n+len(da.low)+len(da.runes)+2*len(da.high)+2*len(da.entries)+len(da.unused))
+	image = append(image, uint32(n), uint32(len(da.low)), uint32(len(da.runes)),
+		uint32(len(da.high)), uint32(len(da.entries)), uint32(len(da.unused)))
+	for _, a := range [][]int32{da.base, da.check, da.value, da.first, da.sibling, da.low, da.runes} {
+		for _, v := range a {
+			image = append(image, uint32(v))
+		}
+	}
+	high := make([]rune, 0, len(da.high))
+	for r := range da.high {
+		high = append(high, r)
+	}
+	sort.Slice(high, func(i, j int) bool { return high[i] < high[j] })
+	for _, r := range high {
+		image = append(image, uint32(r), uint32(da.high[r]))
+	}
+	for _, en := range da.entries {
+		image = append(image, e.String(en.word), e.String(en.variant))
+	}
+	for _, v := range da.unused {
+		image = append(image, uint32(v))
+	}
+	e.Image(image)
+}
+
+// loadImage 从词典中的映像创建双数组，可能时数组直接引用词典的数据(零拷贝)，修改前再复制
+func loadImage(d *wordfilter.DictDecoder) (*DoubleArray, error) {
+	words, shared := wordfilter.Uint32s(d.Image())
+	if len(words) < daImageHeader {
+		return nil, wordfilter.ErrDictFormat
+	}
+	var sizes [daImageHeader]uint64
+	total := uint64(daImageHeader)
+	for i := range sizes {
+		sizes[i] = uint64(words[i])
+	}
+	total += 5*sizes[0] + sizes[1] + sizes[2] + 2*sizes[3] + 2*sizes[4] + sizes[5]
+	if total != uint64(len(words)) || sizes[0] <= daRoot || sizes[2] == 0 || sizes[1] > daLowRunes {
+		return nil, wordfilter.ErrDictFormat
+	}
+	ints := *(*[]int32)(unsafe.Pointer(&words))
+	ints = ints[daImageHeader:]
+	take := func(n uint64) []int32 {
+		a := ints[:n:n]
+		ints = ints[n:]
+		return a
+	}
+	da := &DoubleArray{shared: shared}
+	da.base, da.check, da.value, da.first, da.sibling = take(sizes[0]), take(sizes[0]), take(sizes[0]), take(sizes[0]), take(sizes[0])
+	da.low, da.runes = take(sizes[1]), take(sizes[2])
+	if high := take(2 * sizes[3]); len(high) > 0 {
+		da.high = make(map[rune]int32, len(high)/2)
+		for i := 0; i < len(high); i += 2 {
+			da.high[high[i]] = high[i+1]
+		}
+	}
+	entries := take(2 * sizes[4])
+	da.entries = make([]entry, len(entries)/2)
+	for i := range da.entries {
+		word, err := d.String(uint32(entries[2*i]))
+		if err != nil {
+			return nil, err
+		}
+		variant, err := d.String(uint32(entries[2*i+1]))
+		if err != nil {
+			return nil, err
+		}
+		da.entries[i] = entry{word: word, variant: variant}
+	}
+	da.unused = append([]int32(nil), take(sizes[5])...)
+	if !da.valid() {
+		return nil, wordfilter.ErrDictFormat
+	}
+	return da, nil
+}
+
+// valid 检查映像中的下标都在范围内，避免查找时越界
+func (da *DoubleArray) valid() bool {
+	n, codes := int32(len(da.check)), int32(len(da.runes))
+	for s := int32(0); s < n; s++ {
+		if v := da.value[s]; v < 0 || int(v) > len(da.entries) {
+			return false
+		}
+		if f := da.first[s]; f < 0 || f > 1<<daDigitBits || (f != 0 && (da.base[s] <= 0 || da.base[s] >= n-f)) {
+			return false
+		}
+		if sib := da.sibling[s]; sib < 0 || sib > 1<<daDigitBits ||
+			(sib != 0 && (da.check[s] <= 0 || da.check[s] >= n || da.base[da.check[s]] <= 0 || da.base[da.check[s]] >= n-sib)) {
+			return false
+		}
+	}
+	for _, c := range da.low {
+		if c < 0 || c >= codes {
+			return false
+		}
+	}
+	for _, c := range da.high {
+		if c <= 0 || c >= codes {
+			return false
+		}
+	}
+	for _, v := range da.unused {
+		if v < 0 || int(v) >= len(da.entries) {
+			return false
+		}
+	}
+	return true
+}
+
+// own 数组引用词典的数据时，修改前复制所有数组
+func (da *DoubleArray) own() {
+	if da.shared {
+		*da = *da.Clone()
+	}
+}
@@ -0,0 +1,676 @@
+package common
+
+import (
+	"sort"
+	"unsafe"
+
+	wordfilter "github.com/hellobchain/sensitivewordfilter/filter"
+)
+
+const (
+	// daRoot 双数组Trie的根状态，位置0为空闲链表的头
+	daRoot = 1
+	// daLowRunes 使用数组映射编码的字符范围(基本多文种平面)，其余字符使用map
+	daLowRunes = 0x10000
+	// daDigitBits 字符编码拆分为两次转移，每次转移的取值范围为 [1, 1<<daDigitBits]
+	daDigitBits = 6
+	// daMaxCandidates 查找 base 时最多尝试的空闲位置个数
+	daMaxCandidates = 256
+)
+
+// DoubleArray 基于双数组的Trie，查找结果与Trie相同，但不需要为每个节点分配map，占用的内存更少
+// 字符先映射为从1开始的连续编码，再拆分为高低两位，每一位为一次转移，使每个状态的子状态分布在较小的范围内；
+// 状态 s 经 d 转移到 t = base[s]+d，且 check[t] == s；
+// 空闲的位置 check 及 base 分别为下一个、上一个空闲位置的相反数
+// DoubleArray 本身不是并发安全的，需要由调用方保证修改与读取不会同时进行
+type DoubleArray struct {
+	base    []int32
+	check   []int32
+	value   []int32 // 词尾状态对应的词条下标加1，非词尾状态为0
+	first   []int32 // 第一个子状态的编码，没有子状态时为0
+	sibling []int32 // 下一个兄弟状态的编码，没有时为0
+
+	low   []int32        // 基本多文种平面字符的编码
+	high  map[rune]int32 // 其余字符的编码
+	runes []rune         // 编码对应的字符，下标0不使用
+
+	entries []entry
+	unused  []int32 // 可复用的词条下标
+
+	shared bool // 数组是否引用二进制词典的数据(零拷贝加载)，修改前需复制
+}
+
+// NewDoubleArray 新建一棵双数组Trie
+func NewDoubleArray() *DoubleArray {
+	da := &DoubleArray{
+		base:    make([]int32, daRoot+1),
+		check:   make([]int32, daRoot+1),
+		value:   make([]int32, daRoot+1),
+		first:   make([]int32, daRoot+1),
+		sibling: make([]int32, daRoot+1),
+		runes:   make([]rune, 1),
+	}
+	return da
+}
+
+// Add 添加若干个词
+func (da *DoubleArray) Add(words ...string) {
+	for _, word := range words {
+		da.AddEntry(word, word, "")
+	}
+}
+
+// AddEntry 与Trie.AddEntry相同
+func (da *DoubleArray) AddEntry(path, word, variant string) {
+	da.own()
+	var current int32 = daRoot
+	var runes = []rune(path)
+	for position := 0; position < len(runes); position++ {
+		current = da.child(current, runes[position])
+		if position == len(runes)-1 {
+			if v := da.value[current]; v != 0 && da.entries[v-1].variant == "" && variant != "" {
+				return
+			}
+			da.setEntry(current, entry{word: word, variant: variant})
+		}
+	}
+}
+
+// Del 删除若干个词
+func (da *DoubleArray) Del(words ...string) {
+	for _, word := range words {
+		da.DelEntry(word, word, "")
+	}
+}
+
+// DelEntry 与Trie.DelEntry相同
+func (da *DoubleArray) DelEntry(path, word, variant string) {
+	da.own()
+	current := da.path(path)
+	if current == daRoot || current == 0 || da.value[current] == 0 {
+		return
+	}
+	if e := da.entries[da.value[current]-1]; e.variant == variant && (variant == "" || e.word == word) {
+		da.prune(current)
+	}
+}
+
+// path 返回 word 对应的状态，不存在时返回0
+func (da *DoubleArray) path(word string) int32 {
+	var current int32 = daRoot
+	for _, r := range word {
+		next, found := da.next(current, r)
+		if !found {
+			return 0
+		}
+		current = next
+	}
+	return current
+}
+
+// prune 删除状态 s 上的词，并向上删除不再是词尾且没有子状态的状态
+func (da *DoubleArray) prune(s int32) {
+	da.setEntry(s, entry{})
+	for s != daRoot && da.value[s] == 0 && da.first[s] == 0 {
+		parent := da.check[s]
+		da.unlinkChild(parent, s-da.base[parent])
+		da.release(s)
+		s = parent
+	}
+}
+
+// setEntry 设置状态 s 对应的词条，空词条表示 s 不再是词尾
+func (da *DoubleArray) setEntry(s int32, e entry) {
+	v := da.value[s]
+	if e == (entry{}) {
+		if v != 0 {
+			da.entries[v-1] = entry{}
+			da.unused = append(da.unused, v-1)
+			da.value[s] = 0
+		}
+		return
+	}
+	if v == 0 {
+		if n := len(da.unused); n > 0 {
+			v = da.unused[n-1] + 1
+			da.unused = da.unused[:n-1]
+		} else {
+			da.entries = append(da.entries, entry{})
+			v = int32(len(da.entries))
+		}
+		da.value[s] = v
+	}
+	da.entries[v-1] = e
+}
+
+// code 返回字符的编码，字符不在任何词中时返回0
+func (da *DoubleArray) code(r rune) int32 {
+	if r >= 0 && int(r) < len(da.low) {
+		return da.low[r]
+	}
+	if r >= 0 && r < daLowRunes {
+		return 0
+	}
+	return da.high[r]
+}
+
+// encode 返回字符的编码，不存在时分配新的编码
+func (da *DoubleArray) encode(r rune) int32 {
+	if c := da.code(r); c != 0 {
+		return c
+	}
+	c := int32(len(da.runes))
+	da.runes = append(da.runes, r)
+	if r >= 0 && r < daLowRunes {
+		if int(r) >= len(da.low) {
+			size := 2 * len(da.low)
+			if size <= int(r) {
+				size = int(r) + 1
+			}
+			if size > daLowRunes {
+				size = daLowRunes
+			}
+			low := make([]int32, size)
+			copy(low, da.low)
+			da.low = low
+		}
+		da.low[r] = c
+		return c
+	}
+	if da.high == nil {
+		da.high = make(map[rune]int32)
+	}
+	da.high[r] = c
+	return c
+}
+
+// digits 将字符编码拆分为高低两位
+func digits(c int32) (int32, int32) {
+	return c>>daDigitBits + 1, c&(1<<daDigitBits-1) + 1
+}
+
+// next 返回状态 s 经字符 r 转移到的状态
+func (da *DoubleArray) next(s int32, r rune) (int32, bool) {
+	c := da.code(r)
+	if c == 0 {
+		return 0, false
+	}
+	hi, lo := digits(c)
+	if s = da.transit(s, hi); s == 0 {
+		return 0, false
+	}
+	s = da.transit(s, lo)
+	return s, s != 0
+}
+
+// transit 返回状态 s 经 d 转移到的状态，不存在时返回0
+func (da *DoubleArray) transit(s, d int32) int32 {
+	if da.base[s] <= 0 {
+		return 0
+	}
+	if t := da.base[s] + d; int(t) < len(da.check) && da.check[t] == s {
+		return t
+	}
+	return 0
+}
+
+// child 返回状态 s 经字符 r 转移到的状态，不存在时创建
+func (da *DoubleArray) child(s int32, r rune) int32 {
+	hi, lo := digits(da.encode(r))
+	return da.childDigit(da.childDigit(s, hi), lo)
+}
+
+// childDigit 返回状态 s 经 d 转移到的状态，不存在时创建
+func (da *DoubleArray) childDigit(s, d int32) int32 {
+	if t := da.transit(s, d); t != 0 {
+		return t
+	}
+	if da.base[s] == 0 {
+		da.base[s] = da.findBase([]int32{d})
+	} else if t := da.base[s] + d; !da.free(t) {
+		// 位置已被其它状态的子状态占用，迁移子状态较少的一方
+		other := da.check[t]
+		codes := append(da.children(s), d)
+		if others := da.children(other); other == daRoot || len(codes) <= len(others) {
+			da.relocate(s, da.findBase(codes), 0)
+		} else {
+			s = da.relocate(other, da.findBase(others), s)
+		}
+	}
+	t := da.base[s] + d
+	da.take(t)
+	da.check[t] = s
+	da.sibling[t] = da.first[s]
+	da.first[s] = d
+	return t
+}
+
+func (da *DoubleArray) root() cursor {
+	return cursor{state: daRoot}
+}
+
+func (da *DoubleArray) step(c cursor, r rune) (cursor, bool) {
+	next, found := da.next(c.state, r)
+	return cursor{state: next}, found
+}
+
+func (da *DoubleArray) at(c cursor) (entry, bool, bool) {
+	var e entry
+	v := da.value[c.state]
+	if v != 0 {
+		e = da.entries[v-1]
+	}
+	return e, v != 0, da.first[c.state] == 0
+}
+
+// children 返回状态 s 所有子状态的转移
+func (da *DoubleArray) children(s int32) []int32 {
+	var codes []int32
+	for c := da.first[s]; c != 0; c = da.sibling[da.base[s]+c] {
+		codes = append(codes, c)
+	}
+	return codes
+}
+
+// unlinkChild 从状态 s 的子状态链表中移除转移 c
+func (da *DoubleArray) unlinkChild(s, c int32) {
+	if da.first[s] == c {
+		da.first[s] = da.sibling[da.base[s]+c]
+		return
+	}
+	for prev := da.first[s]; prev != 0; prev = da.sibling[da.base[s]+prev] {
+		if da.sibling[da.base[s]+prev] == c {
+			da.sibling[da.base[s]+prev] = da.sibling[da.base[s]+c]
+			return
+		}
+	}
+}
+
+// relocate 将状态 s 的所有子状态迁移到新的 base，返回状态 track 迁移后的位置
+func (da *DoubleArray) relocate(s, base, track int32) int32 {
+	old := da.base[s]
+	for c := da.first[s]; c != 0; {
+		from, to := old+c, base+c
+		da.take(to)
+		da.check[to] = s
+		da.base[to] = da.base[from]
+		da.value[to] = da.value[from]
+		da.first[to] = da.first[from]
+		da.sibling[to] = da.sibling[from]
+		for gc := da.first[from]; gc != 0; gc = da.sibling[da.base[from]+gc] {
+			da.check[da.base[from]+gc] = to
+		}
+		if track == from {
+			track = to
+		}
+		c = da.sibling[from]
+		da.release(from)
+	}
+	da.base[s] = base
+	return track
+}
+
+// findBase 查找可以容纳所有转移 codes 的 base
+func (da *DoubleArray) findBase(codes []int32) int32 {
+	min := codes[0]
+	for _, c := range codes {
+		if c < min {
+			min = c
+		}
+	}
+	// 最多尝试 daMaxCandidates 个空闲位置，之后使用数组末尾的位置，避免空闲位置较多时逐个检查
+	for p, n := -da.check[0], 0; p != 0 && n < daMaxCandidates; p, n = -da.check[p], n+1 {
+		base := p - min
+		if base < 1 {
+			continue
+		}
+		found := true
+		for _, c := range codes {
+			if !da.free(base + c) {
+				found = false
+				break
+			}
+		}
+		if found {
+			return base
+		}
+	}
+	if base := int32(len(da.check)) - min; base > 1 {
+		return base
+	}
+	return 1
+}
+
+// free 位置 p 是否空闲，超出数组的位置视为空闲
+func (da *DoubleArray) free(p int32) bool {
+	return int(p) >= len(da.check) || da.check[p] <= 0
+}
+
+// take 从空闲链表中取出位置 p，必要时扩容
+func (da *DoubleArray) take(p int32) {
+	if n := len(da.check); int(p) >= n {
+		size := 2 * n
+		if size <= int(p) {
+			size = int(p) + 1
+		}
+		da.grow(size)
+	}
+	next, prev := -da.check[p], -da.base[p]
+	da.check[prev] = -next
+	da.base[next] = -prev
+	da.base[p] = 0
+	da.value[p] = 0
+	da.first[p] = 0
+	da.sibling[p] = 0
+}
+
+// release 将位置 p 加入空闲链表
+func (da *DoubleArray) release(p int32) {
+	da.value[p] = 0
+	da.first[p] = 0
+	da.sibling[p] = 0
+	next := -da.check[0]
+	da.check[p] = -next
+	da.base[p] = 0
+	da.base[next] = -p
+	da.check[0] = -p
+}
+
+// grow 扩容到 size 个位置，新的位置加入空闲链表
+func (da *DoubleArray) grow(size int) {
+	n := len(da.check)
+	da.base = append(da.base, make([]int32, size-n)...)
+	da.check = append(da.check, make([]int32, size-n)...)
+	da.value = append(da.value, make([]int32, size-n)...)
+	da.first = append(da.first, make([]int32, size-n)...)
+	da.sibling = append(da.sibling, make([]int32, size-n)...)
+	for p := size - 1; p >= n; p-- {
+		da.release(int32(p))
+	}
+}
+
+// Clone 返回双数组Trie的副本，复制所有数组
+func (da *DoubleArray) Clone() *DoubleArray {
+	c := &DoubleArray{
+		base:    append([]int32(nil), da.base...),
+		check:   append([]int32(nil), da.check...),
+		value:   append([]int32(nil), da.value...),
+		first:   append([]int32(nil), da.first...),
+		sibling: append([]int32(nil), da.sibling...),
+		low:     append([]int32(nil), da.low...),
+		runes:   append([]rune(nil), da.runes...),
+		entries: append([]entry(nil), da.entries...),
+		unused:  append([]int32(nil), da.unused...),
+	}
+	if da.high != nil {
+		c.high = make(map[rune]int32, len(da.high))
+		for r, code := range da.high {
+			c.high[r] = code
+		}
+	}
+	return c
+}
+
+func (da *DoubleArray) clone() index {
+	return da.Clone()
+}
+
+// walk 按字符顺序先序遍历所有节点，path 为根状态到该节点的字符，不包括高位转移的中间状态
+func (da *DoubleArray) walk(fn func(s int32, path []rune, children int)) {
+	type edge struct {
+		r rune
+		t int32
+	}
+	var path []rune
+	var visit func(s int32)
+	visit = func(s int32) {
+		var edges []edge
+		for hi := da.first[s]; hi != 0; hi = da.sibling[da.base[s]+hi] {
+			middle := da.base[s] + hi
+			for lo := da.first[middle]; lo != 0; lo = da.sibling[da.base[middle]+lo] {
+				c := (hi-1)<<daDigitBits | (lo - 1)
+				edges = append(edges, edge{r: da.runes[c], t: da.base[middle] + lo})
+			}
+		}
+		sort.Slice(edges, func(i, j int) bool { return edges[i].r < edges[j].r })
+		fn(s, path, len(edges))
+		for _, e := range edges {
+			path = append(path, e.r)
+			visit(e.t)
+			path = path[:len(path)-1]
+		}
+	}
+	visit(daRoot)
+}
+
+// Compact 按字符顺序重新插入所有词，去除删除词后留下的空闲位置及不再使用的编码
+func (da *DoubleArray) Compact() {
+	c := NewDoubleArray()
+	da.walk(func(s int32, path []rune, _ int) {
+		if v := da.value[s]; v != 0 {
+			e := da.entries[v-1]
+			c.AddEntry(string(path), e.word, e.variant)
+		}
+	})
+	*da = *c
+}
+
+// Stats 获取节点个数(不包括中间状态)、词尾节点个数及估算占用的内存
+func (da *DoubleArray) Stats() wordfilter.Stats {
+	var stats wordfilter.Stats
+	da.walk(func(s int32, path []rune, _ int) {
+		stats.Nodes++
+		if da.value[s] != 0 {
+			stats.Words++
+			if len(path) > stats.Depth {
+				stats.Depth = len(path)
+			}
+		}
+	})
+	cell := int64(unsafe.Sizeof(int32(0)))
+	stats.Bytes = int64(len(da.check))*5*cell + int64(len(da.low))*cell +
+		int64(len(da.runes))*int64(unsafe.Sizeof(rune(0))) + int64(len(da.unused))*cell +
+		wordfilter.MapBytes(len(da.high), unsafe.Sizeof(rune(0))+unsafe.Sizeof(int32(0))) +
+		int64(len(da.entries))*int64(unsafe.Sizeof(entry{}))
+	for _, e := range da.entries {
+		stats.Bytes += int64(len(e.word) + len(e.variant))
+	}
+	return stats
+}
+
+// Replace 与Trie.Replace相同
+func (da *DoubleArray) Replace(text string, character rune) string {
+	var (
+		parent  int32 = daRoot
+		current int32
+		runes   = []rune(text)
+		length  = len(runes)
+		left    = 0
+		found   bool
+	)
+
+	for position := 0; position < len(runes); position++ {
+		current, found = da.next(parent, runes[position])
+
+		if !found || (da.value[current] == 0 && position == length-1) {
+			parent = daRoot
+			position = left
+			left++
+			continue
+		}
+
+		if da.value[current] != 0 && left <= position {
+			for i := left; i <= position; i++ {
+				runes[i] = character
+			}
+		}
+
+		parent = current
+	}
+
+	return string(runes)
+}
+
+// Filter 与Trie.Filter相同
+func (da *DoubleArray) Filter(text string) string {
+	var (
+		parent      int32 = daRoot
+		current     int32
+		left        = 0
+		found       bool
+		runes       = []rune(text)
+		length      = len(runes)
+		resultRunes = make([]rune, 0, length)
+	)
+
+	for position := 0; position < length; position++ {
+		current, found = da.next(parent, runes[position])
+
+		if !found || (da.value[current] == 0 && position == length-1) {
+			resultRunes = append(resultRunes, runes[left])
+			parent = daRoot
+			position = left
+			left++
+			continue
+		}
+
+		if da.value[current] != 0 {
+			left = position + 1
+			parent = daRoot
+		} else {
+			parent = current
+		}
+	}
+
+	resultRunes = append(resultRunes, runes[left:]...)
+	return string(resultRunes)
+}
+
+// Validate 与Trie.Validate相同
+func (da *DoubleArray) Validate(text string) (bool, string) {
+	var (
+		parent  int32 = daRoot
+		current int32
+		runes   = []rune(text)
+		length  = len(runes)
+		left    = 0
+		found   bool
+	)
+
+	for position := 0; position < len(runes); position++ {
+		current, found = da.next(parent, runes[position])
+
+		if !found || (da.value[current] == 0 && position == length-1) {
+			parent = daRoot
+			position = left
+			left++
+			continue
+		}
+
+		if da.value[current] != 0 && left <= position {
+			return false, string(runes[left : position+1])
+		}
+
+		parent = current
+	}
+
+	return true, ""
+}
+
+// FindAll 与Trie.FindAll相同
+func (da *DoubleArray) FindAll(text string) []string {
+	var matches []string
+	set := make(map[string]struct{})
+	da.findAll(text, func(word string) {
+		if _, ok := set[word]; !ok {
+			set[word] = struct{}{}
+			matches = append(matches, word)
+		}
+	})
+	return matches
+}
+
+// FindAllMap 与Trie.FindAllMap相同
+func (da *DoubleArray) FindAllMap(text string, data map[string]int) {
+	da.findAll(text, func(word string) {
+		data[word]++
+	})
+}
+
+func (da *DoubleArray) findAll(text string, fn func(word string)) {
+	var (
+		parent  int32 = daRoot
+		current int32
+		runes   = []rune(text)
+		length  = len(runes)
+		left    = 0
+		found   bool
+	)
+
+	for position := 0; position < length; position++ {
+		current, found = da.next(parent, runes[position])
+
+		if !found {
+			parent = daRoot
+			position = left
+			left++
+			continue
+		}
+
+		if da.value[current] != 0 && left <= position {
+			fn(string(runes[left : position+1]))
+		}
+
+		if position == length-1 {
+			parent = daRoot
+			position = left
+			left++
+			continue
+		}
+
+		parent = current
+	}
+}
+
+func (da *DoubleArray) shortest(runes []rune, left int) int {
+	var current int32 = daRoot
+	for position := left; position < len(runes); position++ {
+		next, found := da.next(current, runes[position])
+		if !found {
+			return -1
+		}
+		current = next
+		if da.value[current] != 0 {
+			return position + 1
+		}
+	}
+	return -1
+}
+
+func (da *DoubleArray) scan(runes []rune, o scanOptions, fn func(start, end int, e entry, gaps []int) bool) {
+	var gaps []int
+	for left, length := 0, len(runes); left < length; left++ {
+		if o.wholeWord && !wordfilter.IsWordStart(runes, left) {
+			continue
+		}
+		var current int32 = daRoot
+		gap := 0
+		gaps = gaps[:0]
+		for position := left; position < length; position++ {
+			next, found := da.next(current, runes[position])
+			if !found {
+				if current == daRoot || gap >= o.maxGap || (o.class != nil && !o.class(runes[position])) {
+					break
+				}
+				gap++
+				gaps = append(gaps, position)
+				continue
+			}
+			gap = 0
+			current = next
+			if v := da.value[current]; v != 0 && o.accept(runes, left, position+1, da.entries[v-1].variant) && !fn(left, position+1, da.entries[v-1], gaps) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package common
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	wordfilter "github.com/hellobchain/sensitivewordfilter/filter"
+)
+
+// randomWords 生成 n 个由常用汉字组成的词，部分词包含字母及扩展区汉字
+func randomWords(rng *rand.Rand, n, chars int) []string {
+	words := make([]string, n)
+	for i := range words {
+		runes := make([]rune, 2+rng.Intn(4))
+		for j := range runes {
+			switch k := rng.Intn(50); {
+			case k == 0:
+				runes[j] = 'a' + rune(rng.Intn(26))
+			case k == 1:
+				runes[j] = 0x20000 + rune(rng.Intn(16))
+			default:
+				runes[j] = 0x4E00 + rune(rng.Intn(chars))
+			}
+		}
+		words[i] = string(runes)
+	}
+	return words
+}
+
+// randomText 由敏感词片段及随机汉字组成待检测文本
+func randomText(rng *rand.Rand, words []string, chars int) string {
+	var text []rune
+	for len(text) < 64 {
+		if rng.Intn(3) == 0 {
+			w := []rune(words[rng.Intn(len(words))])
+			text = append(text, w[:1+rng.Intn(len(w))]...)
+		} else {
+			text = append(text, 0x4E00+rune(rng.Intn(chars)))
+		}
+	}
+	return string(text)
+}
+
+func sameResults(t *testing.T, trie *Trie, da *DoubleArray, texts []string) {
+	t.Helper()
+	type match struct {
+		start, end int
+		e          entry
+		gaps       []int
+	}
+	collect := func(ix index, runes []rune) []match {
+		var matches []match
+		ix.scan(runes, scanOptions{maxGap: 1}, func(start, end int, e entry, gaps []int) bool {
+			matches = append(matches, match{start, end, e, append([]int(nil), gaps...)})
+			return true
+		})
+		return matches
+	}
+	for _, text := range texts {
+		runes := []rune(text)
+		if got, expect := da.Replace(text, '*'), trie.Replace(text, '*'); got != expect {
+			t.Fatalf("Replace(%q) got %q, expect %q", text, got, expect)
+		}
+		if got, expect := da.Filter(text), trie.Filter(text); got != expect {
+			t.Fatalf("Filter(%q) got %q, expect %q", text, got, expect)
+		}
+		ok1, first1 := da.Validate(text)
+		ok2, first2 := trie.Validate(text)
+		if ok1 != ok2 || first1 != first2 {
+			t.Fatalf("Validate(%q) got %v %q, expect %v %q", text, ok1, first1, ok2, first2)
+		}
+		if got, expect := da.FindAll(text), trie.FindAll(text); !reflect.DeepEqual(got, expect) {
+			t.Fatalf("FindAll(%q) got %q, expect %q", text, got, expect)
+		}
+		got, expect := make(map[string]int), make(map[string]int)
+		da.FindAllMap(text, got)
+		trie.FindAllMap(text, expect)
+		if !reflect.DeepEqual(got, expect) {
+			t.Fatalf("FindAllMap(%q) got %v, expect %v", text, got, expect)
+		}
+		for left := range runes {
+			if got, expect := da.shortest(runes, left), trie.shortest(runes, left); got != expect {
+				t.Fatalf("shortest(%q, %d) got %d, expect %d", text, left, got, expect)
+			}
+		}
+		if got, expect := collect(da, runes), collect(trie, runes); !reflect.DeepEqual(got, expect) {
+			t.Fatalf("scan(%q) got %+v, expect %+v", text, got, expect)
+		}
+	}
+	daData, _ := da.MarshalBinary()
+	trieData, _ := trie.MarshalBinary()
+	if !bytes.Equal(daData, trieData) {
+		t.Fatalf("expect identical binary dictionary")
+	}
+	if got, expect := da.Stats(), trie.Stats(); got.Nodes != expect.Nodes || got.Words != expect.Words || got.Depth != expect.Depth {
+		t.Fatalf("got stats %+v, expect %+v", got, expect)
+	}
+}
+
+func TestDoubleArray(t *testing.T) {
+	const chars = 200
+	rng := rand.New(rand.NewSource(1))
+	words := randomWords(rng, 3000, chars)
+	trie, da := NewTrie(), NewDoubleArray()
+	for i, word := range words {
+		trie.Add(word)
+		da.Add(word)
+		if i%3 == 0 {
+			// 派生写法不会覆盖已存在的敏感词
+			variant := words[rng.Intn(len(words))]
+			trie.AddEntry(variant, word, "pinyin")
+			da.AddEntry(variant, word, "pinyin")
+		}
+	}
+	texts := make([]string, 200)
+	for i := range texts {
+		texts[i] = randomText(rng, words, chars)
+	}
+	sameResults(t, trie, da, texts)
+
+	for i, word := range words {
+		switch i % 4 {
+		case 0:
+			trie.Del(word)
+			da.Del(word)
+		case 1:
+			trie.DelEntry(word, word, "")
+			da.DelEntry(word, word, "")
+		}
+	}
+	sameResults(t, trie, da, texts)
+
+	clone := da.Clone()
+	da.Compact()
+	sameResults(t, trie, da, texts)
+
+	loaded := NewDoubleArray()
+	data, _ := trie.MarshalBinary()
+	if err := loaded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	sameResults(t, trie, loaded, texts)
+
+	// 映像加载的数组直接引用词典的数据，修改前复制
+	e := wordfilter.NewDictEncoder(0)
+	da.encodeNodes(e)
+	da.encodeImage(e)
+	image := e.Bytes()
+	d, err := wordfilter.NewDictDecoder(image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapped := NewDoubleArray()
+	if err := mapped.decodeNodes(d); err != nil {
+		t.Fatal(err)
+	}
+	if !mapped.shared {
+		t.Errorf("expect arrays shared with the dictionary")
+	}
+	sameResults(t, trie, mapped, texts)
+	original := append([]byte(nil), image...)
+	mapped.Del(words...)
+	if mapped.shared || !bytes.Equal(image, original) {
+		t.Errorf("expect arrays copied before update")
+	}
+
+	clone.Del(words...)
+	if stats := clone.Stats(); stats.Nodes != 1 || stats.Words != 0 {
+		t.Errorf("expect only root left, got %+v", stats)
+	}
+	sameResults(t, trie, da, texts)
+}
+
+func TestDoubleArrayFilter(t *testing.T) {
+	da, trie := NewWithDoubleArray(), New()
+	for _, filter := range []*Filter{da, trie} {
+		filter.AddWord("有一个东西", "一个东西", "一个", "东西", "个东")
+		filter.DelWord("一个", "东西")
+	}
+	const text = "我有一个东西，一个东东西"
+	if got, expect := da.Replace(text, '*'), trie.Replace(text, '*'); got != expect {
+		t.Errorf("Replace got %q, expect %q", got, expect)
+	}
+	if got, expect := da.FindMatches(text), trie.FindMatches(text); !reflect.DeepEqual(got, expect) {
+		t.Errorf("FindMatches got %+v, expect %+v", got, expect)
+	}
+	clone := da.Clone()
+	clone.DelWord("个东")
+	if ok, _ := da.Validate("个东"); ok {
+		t.Errorf("expect original filter unchanged")
+	}
+	if ok, _ := clone.Validate("个东"); !ok {
+		t.Errorf("expect word removed from clone")
+	}
+}
+
+// benchWords 基准测试使用的10万个词
+func benchWords() []string {
+	return randomWords(rand.New(rand.NewSource(2)), 100000, 3500)
+}
+
+func benchmarkBuild(b *testing.B, newIndex func() index) {
+	words := benchWords()
+	b.ReportAllocs()
+	b.ResetTimer()
+	var ix index
+	for i := 0; i < b.N; i++ {
+		ix = newIndex()
+		ix.Add(words...)
+	}
+	stats := ix.Stats()
+	b.ReportMetric(float64(stats.Bytes), "bytes/index")
+	b.ReportMetric(float64(stats.Nodes), "nodes")
+}
+
+func BenchmarkBuildTrie(b *testing.B) {
+	benchmarkBuild(b, func() index { return NewTrie() })
+}
+
+func BenchmarkBuildDoubleArray(b *testing.B) {
+	benchmarkBuild(b, func() index { return NewDoubleArray() })
+}
+
+func benchmarkFindAll(b *testing.B, ix index) {
+	words := benchWords()
+	ix.Add(words...)
+	rng := rand.New(rand.NewSource(3))
+	texts := make([]string, 100)
+	for i := range texts {
+		texts[i] = randomText(rng, words, 3500)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ix.FindAll(texts[i%len(texts)])
+	}
+}
+
+func BenchmarkFindAllTrie(b *testing.B) {
+	benchmarkFindAll(b, NewTrie())
+}
+
+func BenchmarkFindAllDoubleArray(b *testing.B) {
+	benchmarkFindAll(b, NewDoubleArray())
+}
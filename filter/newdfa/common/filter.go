@@ -5,12 +5,26 @@ import (
 	"io"
 	"os"
 	"regexp"
+	"strings"
+	"sync"
+
+	wordfilter "github.com/hellobchain/sensitivewordfilter/filter"
 )
 
 // Filter 敏感词过滤器
 type Filter struct {
-	trie  *Trie
-	noise *regexp.Regexp
+	trie       index
+	noise      *regexp.Regexp
+	normalizer wordfilter.Normalizer
+	expanders  []wordfilter.Expander
+	maxGap     int
+	gapClass   func(r rune) bool
+	wholeWord  bool
+	surface    bool
+	// mux 保护查找与增删敏感词的并发访问
+	mux sync.RWMutex
+	// version 每次修改Trie后递增，流式匹配据此丢弃修改前尚未结束的匹配
+	version uint64
 }
 
 // New 返回一个敏感词过滤器
@@ -21,11 +35,62 @@ func New() *Filter {
 	}
 }
 
+// NewWithDoubleArray 返回一个使用双数组Trie存储敏感词的过滤器，查找结果与New相同，占用的内存更少
+func NewWithDoubleArray() *Filter {
+	filter := New()
+	filter.trie = NewDoubleArray()
+	return filter
+}
+
 // UpdateNoisePattern 更新去噪模式
 func (filter *Filter) UpdateNoisePattern(pattern string) {
 	filter.noise = regexp.MustCompile(pattern)
 }
 
+// SetNormalizer 设置匹配前的归一化处理，敏感词及待检测文本都会经过相同的归一化
+// 需在添加敏感词之前设置
+func (filter *Filter) SetNormalizer(normalizer wordfilter.Normalizer) {
+	filter.normalizer = normalizer
+}
+
+// Normalizer 匹配前的归一化处理
+func (filter *Filter) Normalizer() wordfilter.Normalizer {
+	return filter.normalizer
+}
+
+// SetExpanders 设置派生敏感词其他写法(如拼音)的扩展
+// 需在添加敏感词之前设置
+func (filter *Filter) SetExpanders(expanders ...wordfilter.Expander) {
+	filter.expanders = expanders
+}
+
+// SetMaxGap 允许敏感词相邻两个字符之间间隔最多 n 个字符，class 限定可以作为间隔的字符，
+// 为nil时任意字符都可以作为间隔；直接过滤敏感词的Filter不受影响
+func (filter *Filter) SetMaxGap(n int, class func(r rune) bool) {
+	filter.maxGap = n
+	filter.gapClass = class
+}
+
+// SetWholeWord 设置拉丁、西里尔等文字的敏感词是否只按整词匹配，汉字等文字的敏感词仍按子串匹配
+func (filter *Filter) SetWholeWord(wholeWord bool) {
+	filter.wholeWord = wholeWord
+}
+
+// WholeWord 拉丁、西里尔等文字的敏感词是否只按整词匹配
+func (filter *Filter) WholeWord() bool {
+	return filter.wholeWord
+}
+
+// SetSurfaceText 设置查找结果是否为原文中的命中文本，而不是归一化后的文本
+func (filter *Filter) SetSurfaceText(surface bool) {
+	filter.surface = surface
+}
+
+// MaxGap 敏感词相邻两个字符之间最多允许间隔的字符个数
+func (filter *Filter) MaxGap() int {
+	return filter.maxGap
+}
+
 // LoadWordDict 加载敏感词字典
 func (filter *Filter) LoadWordDict(path string) error {
 	f, err := os.Open(path)
@@ -48,55 +113,336 @@ func (filter *Filter) Load(rd io.Reader) error {
 			}
 			break
 		}
-		filter.trie.Add(string(line))
+		filter.AddWord(string(line))
 	}
 
 	return nil
 }
 
-// AddWord 添加敏感词
+// AddWord 添加敏感词，可与查找并发调用
 func (filter *Filter) AddWord(words ...string) {
-	filter.trie.Add(words...)
+	filter.mux.Lock()
+	defer filter.mux.Unlock()
+	filter.version++
+	words = trimWords(words)
+	if filter.normalizer == nil && len(filter.expanders) == 0 {
+		filter.trie.Add(words...)
+		return
+	}
+	for _, word := range words {
+		filter.trie.AddEntry(filter.normalize(word), word, "")
+		for _, e := range filter.expanders {
+			for _, v := range e.Expand(word) {
+				filter.trie.AddEntry(filter.normalize(v.Text), word, v.Kind)
+			}
+		}
+	}
+}
+
+// trimWords 去除敏感词首尾的空白，并忽略空的敏感词
+func trimWords(words []string) []string {
+	trimmed := make([]string, 0, len(words))
+	for _, word := range words {
+		if word = strings.TrimSpace(word); word != "" {
+			trimmed = append(trimmed, word)
+		}
+	}
+	return trimmed
+}
+
+// Clone 返回与原过滤器共享Trie节点的副本，之后修改原过滤器或副本时都将复制共享的节点(写时复制)，两者互不影响
+func (filter *Filter) Clone() *Filter {
+	filter.mux.Lock()
+	defer filter.mux.Unlock()
+	return &Filter{
+		trie:       filter.trie.clone(),
+		noise:      filter.noise,
+		normalizer: filter.normalizer,
+		expanders:  filter.expanders,
+		maxGap:     filter.maxGap,
+		gapClass:   filter.gapClass,
+		wholeWord:  filter.wholeWord,
+		surface:    filter.surface,
+	}
+}
+
+// Compact 压缩Trie，释放删除敏感词后残留的空间
+func (filter *Filter) Compact() {
+	filter.mux.Lock()
+	defer filter.mux.Unlock()
+	filter.version++
+	filter.trie.Compact()
 }
 
-// DelWord 删除敏感词
+// Stats 获取Trie的统计信息
+func (filter *Filter) Stats() wordfilter.Stats {
+	filter.mux.RLock()
+	defer filter.mux.RUnlock()
+	return filter.trie.Stats()
+}
+
+// EncodeDict 将Trie的所有节点写入二进制词典，双数组Trie同时写入可以零拷贝加载的映像
+func (filter *Filter) EncodeDict(e *wordfilter.DictEncoder) {
+	filter.mux.RLock()
+	defer filter.mux.RUnlock()
+	filter.trie.encodeNodes(e)
+	if da, ok := filter.trie.(*DoubleArray); ok {
+		da.encodeImage(e)
+	}
+}
+
+// DecodeDict 以二进制词典替换Trie，加载时不再对敏感词进行归一化及派生
+// 双数组Trie优先使用词典中的映像，数组直接引用词典的数据，词典的数据之后不可修改
+func (filter *Filter) DecodeDict(d *wordfilter.DictDecoder) error {
+	filter.mux.Lock()
+	defer filter.mux.Unlock()
+	if err := filter.trie.decodeNodes(d); err != nil {
+		return err
+	}
+	filter.version++
+	return nil
+}
+
+// DelWord 删除敏感词，可与查找并发调用
 func (filter *Filter) DelWord(words ...string) {
-	filter.trie.Del(words...)
+	filter.mux.Lock()
+	defer filter.mux.Unlock()
+	filter.version++
+	for _, word := range trimWords(words) {
+		filter.trie.DelEntry(filter.normalize(word), word, "")
+		for _, e := range filter.expanders {
+			for _, v := range e.Expand(word) {
+				filter.trie.DelEntry(filter.normalize(v.Text), word, v.Kind)
+			}
+		}
+	}
 }
 
 // Filter 过滤敏感词
 func (filter *Filter) Filter(text string) string {
-	return filter.trie.Filter(text)
+	filter.mux.RLock()
+	defer filter.mux.RUnlock()
+	if filter.normalizer == nil && !filter.bounded() {
+		return filter.trie.Filter(text)
+	}
+	t := wordfilter.NewText(text, wordfilter.NewMatchOptions(), nil, filter.normalizer)
+	runes := []rune(text)
+	removed := make([]bool, len(runes))
+	remove := func(start, end int) {
+		m := t.Match(start, end)
+		for i := m.Start; i < m.End; i++ {
+			removed[i] = true
+		}
+	}
+	if filter.bounded() {
+		// 从左到右删除位于单词边界的最短的词，与 shortest 的选择相同
+		next := 0
+		filter.trie.scan(t.Runes, scanOptions{wholeWord: filter.wholeWord}, func(start, end int, e entry, gaps []int) bool {
+			if start >= next {
+				remove(start, end)
+				next = end
+			}
+			return true
+		})
+	} else {
+		for left := 0; left < len(t.Runes); left++ {
+			end := filter.trie.shortest(t.Runes, left)
+			if end < 0 {
+				continue
+			}
+			remove(left, end)
+			left = end - 1
+		}
+	}
+	result := make([]rune, 0, len(runes))
+	for i, r := range runes {
+		if !removed[i] {
+			result = append(result, r)
+		}
+	}
+	return string(result)
 }
 
-// Replace 和谐敏感词
+// Replace 和谐敏感词，替换所有命中(包括重叠的命中)的字符
 func (filter *Filter) Replace(text string, repl rune) string {
-	return filter.trie.Replace(text, repl)
+	filter.mux.RLock()
+	defer filter.mux.RUnlock()
+	t := wordfilter.NewText(text, wordfilter.NewMatchOptions(), nil, filter.normalizer)
+	runes := []rune(text)
+	filter.scan(t.Runes, func(start, end int, e entry, gaps []int) bool {
+		m := t.Match(start, end)
+		m.Gaps = t.Gaps(gaps)
+		m.Mask(runes, repl)
+		return true
+	})
+	return string(runes)
 }
 
 // FindIn 检测敏感词
 func (filter *Filter) FindIn(text string) (bool, string) {
-	text = filter.RemoveNoise(text)
-	return filter.trie.FindIn(text)
+	validated, first := filter.Validate(text)
+	return !validated, first
 }
 
 // FindAll 找到所有匹配词
 func (filter *Filter) FindAll(text string) []string {
-	return filter.trie.FindAll(text)
+	filter.mux.RLock()
+	defer filter.mux.RUnlock()
+	if filter.plain() && !filter.surfaced() {
+		return filter.trie.FindAll(filter.normalize(text))
+	}
+	var matches []string
+	set := make(map[string]struct{})
+	t := wordfilter.NewText(text, wordfilter.NewMatchOptions(), nil, filter.normalizer)
+	filter.scan(t.Runes, func(start, end int, e entry, gaps []int) bool {
+		word := filter.resultWord(t, start, end, gaps, nil)
+		if _, ok := set[word]; !ok {
+			set[word] = struct{}{}
+			matches = append(matches, word)
+		}
+		return true
+	})
+	return matches
 }
 
 // FindAllMap 找到所有匹配词以及匹配词出现的次数
-func (filter *Filter) FindAllMap(text string, data map[string]int)  {
-	filter.trie.FindAllMap(text, data)
+func (filter *Filter) FindAllMap(text string, data map[string]int) {
+	filter.mux.RLock()
+	defer filter.mux.RUnlock()
+	if filter.plain() && !filter.surfaced() {
+		filter.trie.FindAllMap(filter.normalize(text), data)
+		return
+	}
+	t := wordfilter.NewText(text, wordfilter.NewMatchOptions(), nil, filter.normalizer)
+	filter.scan(t.Runes, func(start, end int, e entry, gaps []int) bool {
+		data[filter.resultWord(t, start, end, gaps, nil)]++
+		return true
+	})
 }
 
-// Validate 检测字符串是否合法
+// FindMatches 找到所有匹配词及其在原文中的位置
+func (filter *Filter) FindMatches(text string, opts ...wordfilter.MatchOption) []wordfilter.Match {
+	o := wordfilter.NewMatchOptions(opts...)
+	matches, _ := filter.findMatches(text, o)
+	return wordfilter.ResolveOverlaps(matches, o.Overlap)
+}
+
+// FindResult 按 opts 找到所有匹配词以及匹配词出现的次数，结果与 FindAllMap 相同
+func (filter *Filter) FindResult(text string, opts ...wordfilter.MatchOption) map[string]int {
+	o := wordfilter.NewMatchOptions(opts...)
+	matches, words := filter.findMatches(text, o)
+	data := make(map[string]int)
+	for _, i := range wordfilter.SelectOverlaps(matches, o.Overlap) {
+		data[words[i]]++
+	}
+	return data
+}
+
+// findMatches 找到所有匹配词在原文中的位置，以及对应的结果文本(见 resultWord)
+func (filter *Filter) findMatches(text string, o *wordfilter.MatchOptions) ([]wordfilter.Match, []string) {
+	filter.mux.RLock()
+	defer filter.mux.RUnlock()
+	t := wordfilter.NewText(text, o, filter.noise, filter.normalizer)
+	var (
+		matches []wordfilter.Match
+		words   []string
+	)
+	filter.scan(t.Runes, func(start, end int, e entry, gaps []int) bool {
+		m := t.Match(start, end)
+		m.Gaps = t.Gaps(gaps)
+		m.Entry = e.word
+		m.Variant = e.variant
+		matches = append(matches, m)
+		words = append(words, filter.resultWord(t, start, end, gaps, o))
+		return true
+	})
+	return matches, words
+}
+
+// Validate 检测字符串是否合法，检测前会去除噪音
 func (filter *Filter) Validate(text string) (bool, string) {
-	text = filter.RemoveNoise(text)
-	return filter.trie.Validate(text)
+	filter.mux.RLock()
+	defer filter.mux.RUnlock()
+	return filter.validate(filter.normalize(filter.RemoveNoise(text)))
+}
+
+// FindFirst 查找文本中的第一个敏感词，与 Validate 不同，查找前不会去除噪音
+func (filter *Filter) FindFirst(text string) (string, bool) {
+	filter.mux.RLock()
+	defer filter.mux.RUnlock()
+	validated, first := filter.validate(filter.normalize(text))
+	return first, !validated
+}
+
+// validate 检测归一化后的字符串是否合法
+func (filter *Filter) validate(text string) (bool, string) {
+	if filter.plain() {
+		return filter.trie.Validate(text)
+	}
+	var (
+		runes     = []rune(text)
+		validated = true
+		first     string
+	)
+	filter.scan(runes, func(start, end int, e entry, gaps []int) bool {
+		validated = false
+		first = string(runes[start:end])
+		return false
+	})
+	return validated, first
 }
 
 // RemoveNoise 去除空格等噪音
 func (filter *Filter) RemoveNoise(text string) string {
 	return filter.noise.ReplaceAllString(text, "")
 }
+
+func (filter *Filter) normalize(text string) string {
+	return wordfilter.NormalizeString(filter.normalizer, text)
+}
+
+func (filter *Filter) scan(runes []rune, fn func(start, end int, e entry, gaps []int) bool) {
+	filter.trie.scan(runes, scanOptions{maxGap: filter.maxGap, class: filter.gapClass, wholeWord: filter.wholeWord}, fn)
+}
+
+// plain 是否可以直接使用Trie自身的查找，即不允许间隔且不需要判断单词边界
+func (filter *Filter) plain() bool {
+	return filter.maxGap == 0 && !filter.bounded()
+}
+
+// bounded 是否需要判断单词边界，整词匹配或派生写法(如拼音)的词需位于单词边界
+func (filter *Filter) bounded() bool {
+	return filter.wholeWord || len(filter.expanders) > 0
+}
+
+// surfaced 查找结果是否需要从归一化后的文本转换为原文
+func (filter *Filter) surfaced() bool {
+	return filter.surface && filter.normalizer != nil
+}
+
+// resultWord 返回命中在查找结果中的文本，即归一化后除间隔字符以外的文本，
+// SetSurfaceText 时为原文中除间隔及 o 中排除的字符以外的文本
+func (filter *Filter) resultWord(t *wordfilter.Text, start, end int, gaps []int, o *wordfilter.MatchOptions) string {
+	if !filter.surface {
+		return wordOf(t.Runes, start, end, gaps)
+	}
+	m := t.Match(start, end)
+	m.Gaps = t.Gaps(gaps)
+	return m.Surface(o)
+}
+
+// wordOf 返回区间 [start, end) 内除间隔字符以外的文本
+func wordOf(runes []rune, start, end int, gaps []int) string {
+	if len(gaps) == 0 {
+		return string(runes[start:end])
+	}
+	word := make([]rune, 0, end-start-len(gaps))
+	for i := start; i < end; i++ {
+		if len(gaps) > 0 && gaps[0] == i {
+			gaps = gaps[1:]
+			continue
+		}
+		word = append(word, runes[i])
+	}
+	return string(word)
+}
@@ -6,6 +6,8 @@ import (
 	"regexp"
 	"strings"
 	"testing"
+
+	wordfilter "github.com/hellobchain/sensitivewordfilter/filter"
 )
 
 func TestLoadDict(t *testing.T) {
@@ -24,7 +26,7 @@ func TestLoad(t *testing.T) {
 	if err != nil {
 		t.Errorf("fail to load dict %v", err)
 	}
-	if len(filter.trie.Root.Children) == 0 {
+	if len(filter.trie.(*Trie).Root.Children) == 0 {
 		t.Errorf("load dict empty")
 	}
 }
@@ -143,10 +145,11 @@ func TestSensitiveReplace(t *testing.T) {
 		DelWords   []string
 		ThenExpect string
 	}{
-		{"我有一个东东西", "我有**东**", []string{"一个"}, "我有一****"},
+		// 重叠的命中都被替换
+		{"我有一个东东西", "我有*****", []string{"一个"}, "我有一****"},
 		{"我有一个东西", "我*****", []string{"有一个东西"}, "我有****"},
 		{"一个东西", "****", []string{"一个东西", "一个", "东西"}, "一**西"},
-		{"两个东西", "两**西", []string{"个东"}, "两个**"},
+		{"两个东西", "两***", []string{"个东"}, "两个**"},
 		{"一个物体", "**物体", []string{"一个"}, "一个物体"},
 	}
 
@@ -356,3 +359,96 @@ func TestIssue13(t *testing.T) {
 		t.Errorf("%v expected, got %v", expected, r)
 	}
 }
+
+func TestFindMatches(t *testing.T) {
+	filter := New()
+	filter.AddWord("暴力")
+	filter.AddWord("文件")
+
+	testcases := []struct {
+		Text   string
+		Opts   []wordfilter.MatchOption
+		Expect []wordfilter.Match
+	}{
+		{"一个暴力文件", nil, []wordfilter.Match{
+			{Word: "暴力", Entry: "暴力", Start: 2, End: 4, ByteStart: 6, ByteEnd: 12},
+			{Word: "文件", Entry: "文件", Start: 4, End: 6, ByteStart: 12, ByteEnd: 18},
+		}},
+		{"a暴#力b", []wordfilter.MatchOption{wordfilter.WithExcludes('#')}, []wordfilter.Match{
+			{Word: "暴#力", Entry: "暴力", Start: 1, End: 4, ByteStart: 1, ByteEnd: 8},
+		}},
+		{"暴 @ 力，文*件", []wordfilter.MatchOption{wordfilter.WithRemoveNoise()}, []wordfilter.Match{
+			{Word: "暴 @ 力", Entry: "暴力", Start: 0, End: 5, ByteStart: 0, ByteEnd: 9},
+			{Word: "文*件", Entry: "文件", Start: 6, End: 9, ByteStart: 12, ByteEnd: 19},
+		}},
+		{"文 件", nil, nil},
+	}
+
+	for _, tc := range testcases {
+		if got := filter.FindMatches(tc.Text, tc.Opts...); !reflect.DeepEqual(tc.Expect, got) {
+			t.Errorf("find matches %s, got %+v, expect %+v", tc.Text, got, tc.Expect)
+		}
+	}
+}
+
+func TestNormalizer(t *testing.T) {
+	filter := New()
+	filter.SetNormalizer(wordfilter.DefaultNormalizer())
+	filter.AddWord("Bad")
+
+	if got := filter.Replace("a ＢＡＤ day", '*'); got != "a *** day" {
+		t.Errorf("replace got %s, expect a *** day", got)
+	}
+	if got := filter.Filter("a ＢＡＤ day"); got != "a  day" {
+		t.Errorf("filter got %s, expect a  day", got)
+	}
+	if pass, first := filter.Validate("BÄD"); pass || first != "bad" {
+		t.Errorf("validate got %v, %s, expect false, bad", pass, first)
+	}
+	filter.DelWord("BAD")
+	if got := filter.FindAll("bad"); got != nil {
+		t.Errorf("find all after del got %v, expect nil", got)
+	}
+}
+
+func TestMaxGap(t *testing.T) {
+	filter := New()
+	filter.SetMaxGap(1, nil)
+	filter.AddWord("暴力")
+	filter.AddWord("文件")
+
+	if got := filter.Replace("暴x力和文件", '*'); got != "*x*和**" {
+		t.Errorf("replace got %s, expect *x*和**", got)
+	}
+	if got := filter.FindAll("暴x力，暴xx力"); !reflect.DeepEqual(got, []string{"暴力"}) {
+		t.Errorf("find all got %v, expect [暴力]", got)
+	}
+	data := make(map[string]int)
+	filter.FindAllMap("暴x力，暴力", data)
+	if !reflect.DeepEqual(data, map[string]int{"暴力": 2}) {
+		t.Errorf("find all map got %v, expect map[暴力:2]", data)
+	}
+	if pass, first := filter.Validate("文。件"); pass || first != "文。件" {
+		t.Errorf("validate got %v, %s, expect false, 文。件", pass, first)
+	}
+}
+
+func TestClone(t *testing.T) {
+	old := New()
+	old.AddWord("暴力", "广告")
+	clone := old.Clone()
+	clone.DelWord("广告")
+	clone.AddWord("暴乱")
+	if ok, _ := old.Validate("广告"); ok {
+		t.Errorf("expect original filter unchanged")
+	}
+	if ok, _ := old.Validate("暴乱"); !ok {
+		t.Errorf("expect original filter unchanged")
+	}
+	if ok, _ := clone.Validate("广告"); !ok {
+		t.Errorf("expect word removed from clone")
+	}
+	if ok, _ := clone.Validate("暴乱暴力"); ok {
+		t.Errorf("expect words found in clone")
+	}
+}
@@ -0,0 +1,83 @@
+package common
+
+import (
+	"context"
+	"io"
+
+	wordfilter "github.com/hellobchain/sensitivewordfilter/filter"
+)
+
+// partial 流式匹配中尚未结束的匹配
+type partial struct {
+	c     cursor
+	start int
+	gap   int
+	gaps  []int
+	// wordStart 起始字符是否满足整词匹配的开头
+	wordStart bool
+}
+
+// FilterStream 从可读流中查找敏感词，每找到一个即回调 fn，fn 返回false时停止查找
+// 匹配状态跨越多次读取保持，占用的内存与最长敏感词的长度成正比；查找期间修改敏感词时，
+// 修改前尚未结束的匹配将被丢弃；整词匹配或命中派生写法时需再读取两个字符才能判断词尾是否为单词边界，回调会相应推迟
+func (filter *Filter) FilterStream(ctx context.Context, reader io.Reader, fn func(wordfilter.Match) bool) error {
+	var (
+		s       = wordfilter.NewStream(ctx, reader, wordfilter.NewMatchOptions(), filter.normalizer)
+		active  []partial
+		next    []partial
+		version uint64
+	)
+	for {
+		r, i, err := s.Next()
+		if err == io.EOF {
+			s.Flush(true, fn)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		next = next[:0]
+		filter.mux.RLock()
+		if filter.version != version {
+			active, version = active[:0], filter.version
+		}
+		root := filter.trie.root()
+		if wordStart := s.WordStart(); wordStart || !filter.wholeWord {
+			active = append(active, partial{c: root, start: i, wordStart: wordStart})
+		}
+		for _, p := range active {
+			c, found := filter.trie.step(p.c, r)
+			if !found {
+				if p.c == root || p.gap >= filter.maxGap || (filter.gapClass != nil && !filter.gapClass(r)) {
+					continue
+				}
+				p.gap++
+				p.gaps = append(p.gaps[:len(p.gaps):len(p.gaps)], i)
+				next = append(next, p)
+				continue
+			}
+			p.c, p.gap = c, 0
+			e, end, leaf := filter.trie.at(c)
+			// 整词匹配或派生写法(如拼音)时词首及词尾需为单词边界
+			if whole := filter.wholeWord || e.variant != ""; end && (!whole || p.wordStart) {
+				m := s.Match(p.start, i+1, p.gaps)
+				m.Entry = e.word
+				m.Variant = e.variant
+				s.Push(m, whole)
+			}
+			if !leaf {
+				next = append(next, p)
+			}
+		}
+		filter.mux.RUnlock()
+		if !s.Flush(false, fn) {
+			return nil
+		}
+		active, next = next, active
+		release := i + 1
+		if len(active) > 0 {
+			release = active[0].start
+		}
+		s.Release(release)
+	}
+}
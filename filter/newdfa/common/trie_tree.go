@@ -1,14 +1,88 @@
 package common
 
+import (
+	"unsafe"
+
+	wordfilter "github.com/hellobchain/sensitivewordfilter/filter"
+)
+
+// index Filter存储敏感词的结构，Trie基于map实现，DoubleArray基于双数组实现，两者的查找结果相同
+type index interface {
+	Add(words ...string)
+	AddEntry(path, word, variant string)
+	Del(words ...string)
+	DelEntry(path, word, variant string)
+	Replace(text string, character rune) string
+	Filter(text string) string
+	Validate(text string) (bool, string)
+	FindAll(text string) []string
+	FindAllMap(text string, data map[string]int)
+	Compact()
+	Stats() wordfilter.Stats
+	MarshalBinary() ([]byte, error)
+	UnmarshalBinary(data []byte) error
+	// encodeNodes 按先序写入所有节点
+	encodeNodes(e *wordfilter.DictEncoder)
+	// decodeNodes 以词典替换所有词，词典无效时保持不变
+	decodeNodes(d *wordfilter.DictDecoder) error
+
+	// clone 返回互不影响的副本
+	clone() index
+	// shortest 返回runes中从left开始的最短敏感词的结束位置，不存在时返回-1
+	shortest(runes []rune, left int) int
+	// scan 与Trie.ScanWithGap相同，回调词尾对应的词条
+	scan(runes []rune, o scanOptions, fn func(start, end int, e entry, gaps []int) bool)
+	// root 返回根节点的位置
+	root() cursor
+	// step 返回位置 c 经字符 r 转移到的位置，不存在时返回false
+	step(c cursor, r rune) (cursor, bool)
+	// at 返回位置 c 对应的词条及其是否为词尾、是否有子节点
+	at(c cursor) (e entry, end bool, leaf bool)
+}
+
+// cursor 流式匹配时在Trie上的位置，Trie使用 node，DoubleArray使用 state
+type cursor struct {
+	node  *Node
+	state int32
+}
+
+// scanOptions 查找词时的配置
+type scanOptions struct {
+	// maxGap, class 相邻两个字符之间最多允许间隔的字符个数及允许作为间隔的字符
+	maxGap int
+	class  func(r rune) bool
+	// wholeWord 拉丁、西里尔等文字的词只按整词匹配
+	wholeWord bool
+}
+
+// accept 区间 [start, end) 的词是否可以回调，整词匹配或命中派生写法(如拼音)时需位于单词边界
+// 整词匹配时词首已在查找前检查
+func (o scanOptions) accept(runes []rune, start, end int, variant string) bool {
+	if !o.wholeWord && variant == "" {
+		return true
+	}
+	return (o.wholeWord || wordfilter.IsWordStart(runes, start)) && wordfilter.IsWordEnd(runes, end)
+}
+
+// entry 词尾对应的敏感词及派生写法类型
+type entry struct {
+	word    string
+	variant string
+}
+
 // Trie 短语组成的Trie树.
 type Trie struct {
 	Root *Node
+	// owned 写时复制的Trie已复制的节点，为nil时直接修改节点
+	owned map[*Node]bool
 }
 
 // Node Trie树上的一个节点.
 type Node struct {
-	isRootNode bool  // 是否是根节点
-	isPathEnd  bool  // 是否敏感词结束词 判断是否为某个路径的结束
+	isRootNode bool   // 是否是根节点
+	isPathEnd  bool   // 是否敏感词结束词 判断是否为某个路径的结束
+	word       string // 词尾节点对应的敏感词
+	variant    string // 词尾节点对应的派生写法类型，敏感词本身为空
 	Character  rune
 	Children   map[rune]*Node
 }
@@ -28,19 +102,24 @@ func (tree *Trie) Add(words ...string) {
 }
 
 func (tree *Trie) add(word string) {
-	var current = tree.Root
-	var runes = []rune(word)
+	tree.AddEntry(word, word, "")
+}
+
+// AddEntry 添加一个词，path 为词在树上的路径，word 为词尾节点对应的敏感词，
+// variant 为派生写法类型，用于添加经过归一化处理的敏感词或其派生写法
+// 派生写法不会覆盖已存在的敏感词
+func (tree *Trie) AddEntry(path, word, variant string) {
+	var current = tree.mutableRoot()
+	var runes = []rune(path)
 	for position := 0; position < len(runes); position++ {
-		r := runes[position]
-		if next, ok := current.Children[r]; ok {
-			current = next
-		} else {
-			newNode := NewNode(r)
-			current.Children[r] = newNode
-			current = newNode
-		}
+		current = tree.child(current, runes[position], true)
 		if position == len(runes)-1 {
+			if current.isPathEnd && current.variant == "" && variant != "" {
+				return
+			}
 			current.isPathEnd = true
+			current.word = word
+			current.variant = variant
 		}
 	}
 }
@@ -52,20 +131,163 @@ func (tree *Trie) Del(words ...string) {
 }
 
 func (tree *Trie) del(word string) {
-	var current = tree.Root
-	var runes = []rune(word)
-	for position := 0; position < len(runes); position++ {
-		r := runes[position]
-		if next, ok := current.Children[r]; !ok {
+	tree.DelEntry(word, word, "")
+}
+
+// DelEntry 删除 path 对应的敏感词或派生写法，派生写法仅当其属于敏感词 word 时才删除，
+// 敏感词本身(variant 为空)不会删除路径相同的其他敏感词的派生写法
+func (tree *Trie) DelEntry(path, word, variant string) {
+	nodes := tree.path(path)
+	if len(nodes) < 2 {
+		return
+	}
+	if current := nodes[len(nodes)-1]; current.variant == variant && (variant == "" || current.word == word) {
+		tree.prune(nodes)
+	}
+}
+
+// path 获取 word 路径上的节点用于修改，第一个为根节点，路径不存在时返回nil
+func (tree *Trie) path(word string) []*Node {
+	var nodes = []*Node{tree.mutableRoot()}
+	for _, r := range word {
+		current := tree.child(nodes[len(nodes)-1], r, false)
+		if current == nil {
+			return nil
+		}
+		nodes = append(nodes, current)
+	}
+	return nodes
+}
+
+// prune 删除路径 nodes 末尾的词，并自词尾向上删除不再是词尾且没有子节点的节点
+func (tree *Trie) prune(nodes []*Node) {
+	nodes[len(nodes)-1].SoftDel()
+	for i := len(nodes) - 1; i > 0; i-- {
+		if current := nodes[i]; current.isPathEnd || len(current.Children) > 0 {
 			return
-		} else {
-			current = next
 		}
+		delete(nodes[i-1].Children, nodes[i].Character)
+	}
+}
 
-		if position == len(runes)-1 {
-			current.SoftDel()
+// Compact 按实际大小重建所有节点，删除不再通向词尾的节点，之后不再与其它Trie共享节点
+func (tree *Trie) Compact() {
+	root := compactNode(tree.Root)
+	if root == nil {
+		root = NewRootNode(tree.Root.Character)
+	}
+	tree.Root = root
+	tree.owned = nil
+}
+
+// compactNode 复制以 node 为根的子树，不再通向词尾的非根子树返回nil
+func compactNode(node *Node) *Node {
+	c := *node
+	c.Children = make(map[rune]*Node, len(node.Children))
+	if !c.isPathEnd {
+		c.word, c.variant = "", ""
+	}
+	for r, next := range node.Children {
+		if next = compactNode(next); next != nil {
+			c.Children[r] = next
+		}
+	}
+	if !c.isRootNode && !c.isPathEnd && len(c.Children) == 0 {
+		return nil
+	}
+	return &c
+}
+
+// Stats 获取Trie的节点个数、词尾节点个数及估算占用的内存
+func (tree *Trie) Stats() wordfilter.Stats {
+	var stats wordfilter.Stats
+	var walk func(node *Node, depth int)
+	walk = func(node *Node, depth int) {
+		stats.Nodes++
+		stats.Bytes += int64(unsafe.Sizeof(*node)) + wordfilter.MapBytes(len(node.Children), unsafe.Sizeof(rune(0))+unsafe.Sizeof(node))
+		if node.isPathEnd {
+			stats.Words++
+			stats.Bytes += int64(len(node.word) + len(node.variant))
+			if depth > stats.Depth {
+				stats.Depth = depth
+			}
+		}
+		for _, next := range node.Children {
+			walk(next, depth+1)
+		}
+	}
+	walk(tree.Root, 0)
+	return stats
+}
+
+// Clone 返回与原Trie共享节点的副本，之后修改原Trie或副本时都将复制共享的节点(写时复制)，两者互不影响
+// Trie 本身不是并发安全的，需要由调用方保证修改与读取不会同时进行
+func (tree *Trie) Clone() *Trie {
+	tree.owned = make(map[*Node]bool)
+	return &Trie{
+		Root:  tree.Root,
+		owned: make(map[*Node]bool),
+	}
+}
+
+func (tree *Trie) clone() index {
+	return tree.Clone()
+}
+
+func (tree *Trie) scan(runes []rune, o scanOptions, fn func(start, end int, e entry, gaps []int) bool) {
+	tree.scanNodes(runes, o, func(start, end int, node *Node, gaps []int) bool {
+		return fn(start, end, entry{word: node.word, variant: node.variant}, gaps)
+	})
+}
+
+func (tree *Trie) root() cursor {
+	return cursor{node: tree.Root}
+}
+
+func (tree *Trie) step(c cursor, r rune) (cursor, bool) {
+	next, found := c.node.Children[r]
+	return cursor{node: next}, found
+}
+
+func (tree *Trie) at(c cursor) (entry, bool, bool) {
+	return entry{word: c.node.word, variant: c.node.variant}, c.node.isPathEnd, len(c.node.Children) == 0
+}
+
+func (tree *Trie) mutableRoot() *Node {
+	tree.Root = tree.own(tree.Root)
+	return tree.Root
+}
+
+// child 获取节点 current 的子节点 r 用于修改，create 为true时创建不存在的节点，否则返回nil
+func (tree *Trie) child(current *Node, r rune, create bool) *Node {
+	next, ok := current.Children[r]
+	if !ok {
+		if !create {
+			return nil
+		}
+		next = NewNode(r)
+		if tree.owned != nil {
+			tree.owned[next] = true
 		}
+	} else {
+		next = tree.own(next)
 	}
+	current.Children[r] = next
+	return next
+}
+
+// own 写时复制的Trie复制与其它Trie共享的节点，否则返回节点本身
+func (tree *Trie) own(node *Node) *Node {
+	if tree.owned == nil || tree.owned[node] {
+		return node
+	}
+	c := *node
+	c.Children = make(map[rune]*Node, len(node.Children))
+	for r, next := range node.Children {
+		c.Children[r] = next
+	}
+	tree.owned[&c] = true
+	return &c
 }
 
 // Replace 词语替换
@@ -234,7 +456,7 @@ func (tree *Trie) FindAll(text string) []string {
 }
 
 // FindAllMap 找有所有包含在词库中的词
-func (tree *Trie) FindAllMap(text string, data map[string]int)  {
+func (tree *Trie) FindAllMap(text string, data map[string]int) {
 	var matches []string
 	var (
 		parent  = tree.Root
@@ -277,6 +499,66 @@ func (tree *Trie) FindAllMap(text string, data map[string]int)  {
 	}
 }
 
+// shortest 返回runes中从left开始的最短敏感词的结束位置，不存在时返回-1
+func (tree *Trie) shortest(runes []rune, left int) int {
+	current := tree.Root
+	for position := left; position < len(runes); position++ {
+		next, found := current.Children[runes[position]]
+		if !found {
+			return -1
+		}
+		current = next
+		if current.IsPathEnd() {
+			return position + 1
+		}
+	}
+	return -1
+}
+
+// Scan 从每个位置开始查找runes中包含在词库中的词，以其区间 [start, end) 及词尾节点回调fn，
+// fn返回false时停止查找
+func (tree *Trie) Scan(runes []rune, fn func(start, end int, node *Node) bool) {
+	tree.ScanWithGap(runes, 0, nil, func(start, end int, node *Node, _ []int) bool {
+		return fn(start, end, node)
+	})
+}
+
+// ScanWithGap 与Scan相同，但允许词的相邻两个字符之间间隔最多maxGap个字符，
+// class限定可以作为间隔的字符，为nil时任意字符都可以作为间隔
+// 回调fn时gaps为区间内间隔字符的位置，回调之后会被复用；派生写法(如拼音)只按整词匹配
+func (tree *Trie) ScanWithGap(runes []rune, maxGap int, class func(r rune) bool, fn func(start, end int, node *Node, gaps []int) bool) {
+	tree.scanNodes(runes, scanOptions{maxGap: maxGap, class: class}, fn)
+}
+
+// scanNodes 按 o 从每个位置开始查找词，跳过整词匹配时及派生写法不在单词边界上的词
+func (tree *Trie) scanNodes(runes []rune, o scanOptions, fn func(start, end int, node *Node, gaps []int) bool) {
+	var gaps []int
+	for left, length := 0, len(runes); left < length; left++ {
+		if o.wholeWord && !wordfilter.IsWordStart(runes, left) {
+			continue
+		}
+		current := tree.Root
+		gap := 0
+		gaps = gaps[:0]
+		for position := left; position < length; position++ {
+			next, found := current.Children[runes[position]]
+			if !found {
+				if current == tree.Root || gap >= o.maxGap || (o.class != nil && !o.class(runes[position])) {
+					break
+				}
+				gap++
+				gaps = append(gaps, position)
+				continue
+			}
+			gap = 0
+			current = next
+			if current.IsPathEnd() && o.accept(runes, left, position+1, current.variant) && !fn(left, position+1, current, gaps) {
+				return
+			}
+		}
+	}
+}
+
 // NewNode 新建子节点
 func NewNode(character rune) *Node {
 	return &Node{
@@ -304,6 +586,16 @@ func (node *Node) IsRootNode() bool {
 	return node.isRootNode
 }
 
+// Word 词尾节点对应的敏感词
+func (node *Node) Word() string {
+	return node.word
+}
+
+// Variant 词尾节点对应的派生写法类型，敏感词本身为空字符串
+func (node *Node) Variant() string {
+	return node.variant
+}
+
 // IsPathEnd 判断是否为某个路径的结束
 func (node *Node) IsPathEnd() bool {
 	return node.isPathEnd
@@ -12,3 +12,22 @@ func TestTrieTree(t *testing.T) {
 	fmt.Println(tree.Replace("你好吗 我支持习大大， 他的名字叫做习近平", '*'))
 	fmt.Println(tree.Filter("你好吗 我支持习大大， 他的名字叫做习近平"))
 }
+
+func TestTrieDel(t *testing.T) {
+	tree := NewTrie()
+	tree.Add("暴力", "暴力倾向", "广告")
+	tree.Del("暴力倾向", "广告", "不存在")
+	if stats := tree.Stats(); stats.Nodes != 3 || stats.Words != 1 {
+		t.Errorf("expect removed nodes pruned, got %+v", stats)
+	}
+
+	clone := tree.Clone()
+	clone.Del("暴力")
+	clone.Compact()
+	if stats := clone.Stats(); stats.Nodes != 1 || stats.Words != 0 {
+		t.Errorf("expect only root left, got %+v", stats)
+	}
+	if stats := tree.Stats(); stats.Nodes != 3 || stats.Words != 1 {
+		t.Errorf("expect original trie unchanged, got %+v", stats)
+	}
+}
@@ -3,7 +3,9 @@ package newdfa
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"io"
+	"io/ioutil"
 	"unicode"
 
 	"github.com/hellobchain/sensitivewordfilter/filter"
@@ -12,10 +14,8 @@ import (
 
 // NewNodeReaderFilter 创建节点过滤器，实现敏感词的过滤
 // 从可读流中读取敏感词数据(以指定的分隔符读取数据)
-func NewNodeReaderFilter(rd io.Reader, delim byte) filter.SensitivewordFilter {
-	nf := &NodeFilter{
-		filter: common.New(),
-	}
+func NewNodeReaderFilter(rd io.Reader, delim byte, opts ...filter.Option) filter.SensitivewordFilter {
+	nf := newNodeFilter(opts...)
 	buf := new(bytes.Buffer)
 	_, _ = io.Copy(buf, rd)
 	buf.WriteByte(delim)
@@ -35,10 +35,8 @@ func NewNodeReaderFilter(rd io.Reader, delim byte) filter.SensitivewordFilter {
 
 // NewNodeChanFilter 创建节点过滤器，实现敏感词的过滤
 // 从通道中读取敏感词数据
-func NewNodeChanFilter(text <-chan string) filter.SensitivewordFilter {
-	nf := &NodeFilter{
-		filter: common.New(),
-	}
+func NewNodeChanFilter(text <-chan string, opts ...filter.Option) filter.SensitivewordFilter {
+	nf := newNodeFilter(opts...)
 	for v := range text {
 		nf.addSensitiveWords(v)
 	}
@@ -47,18 +45,76 @@ func NewNodeChanFilter(text <-chan string) filter.SensitivewordFilter {
 
 // NewNodeFilter 创建节点过滤器，实现敏感词的过滤
 // 从切片中读取敏感词数据
-func NewNodeFilter(text []string) filter.SensitivewordFilter {
-	nf := &NodeFilter{
-		filter: common.New(),
-	}
+func NewNodeFilter(text []string, opts ...filter.Option) filter.SensitivewordFilter {
+	nf := newNodeFilter(opts...)
 	for i, l := 0, len(text); i < l; i++ {
 		nf.addSensitiveWords(text[i])
 	}
 	return nf
 }
 
+// LoadNodeFilter 从可读流中读取MarshalBinary生成的二进制词典，创建节点过滤器
+// 加载时不再对敏感词进行归一化及派生，opts 中的归一化、派生写法、间隔及整词匹配需与生成词典时一致，否则返回 filter.ErrDictOptions；
+// 词典包含词条的附加信息时返回携带附加信息的 filter.MetaFilter
+func LoadNodeFilter(rd io.Reader, opts ...filter.Option) (filter.SensitivewordFilter, error) {
+	data, err := io.ReadAll(rd)
+	if err != nil {
+		return nil, err
+	}
+	return loadNodeFilter(data, opts...)
+}
+
+// LoadFile 从文件中加载MarshalBinary生成的二进制词典，创建节点过滤器，参见LoadNodeFilter
+// 使用 filter.WithDoubleArray 时以只读方式映射文件，双数组直接引用映射的数据(零拷贝)，增删敏感词时才复制
+func LoadFile(path string, opts ...filter.Option) (filter.SensitivewordFilter, error) {
+	read := ioutil.ReadFile
+	if filter.NewOptions(opts...).DoubleArray {
+		read = filter.MapFile
+	}
+	data, err := read(path)
+	if err != nil {
+		return nil, err
+	}
+	return loadNodeFilter(data, opts...)
+}
+
+func loadNodeFilter(data []byte, opts ...filter.Option) (filter.SensitivewordFilter, error) {
+	nf := newNodeFilter(opts...)
+	d, err := filter.DecodeDict(data, filter.NewOptions(opts...))
+	if err != nil {
+		return nil, err
+	}
+	if err := nf.filter.DecodeDict(d); err != nil {
+		return nil, err
+	}
+	return filter.WrapDictMetas(nf, d), nil
+}
+
 type NodeFilter struct {
 	filter *common.Filter
+	opts   []filter.Option
+}
+
+func newNodeFilter(opts ...filter.Option) *NodeFilter {
+	nf := &NodeFilter{
+		filter: common.New(),
+		opts:   opts,
+	}
+	options := filter.NewOptions(opts...)
+	if options.DoubleArray {
+		nf.filter = common.NewWithDoubleArray()
+	}
+	nf.filter.SetNormalizer(options.Normalizer)
+	nf.filter.SetExpanders(options.Expanders...)
+	nf.filter.SetMaxGap(options.MaxGap, options.GapClass)
+	nf.filter.SetWholeWord(options.WholeWord)
+	nf.filter.SetSurfaceText(options.SurfaceText)
+	return nf
+}
+
+// Options 获取创建过滤器时使用的可选项
+func (nf *NodeFilter) Options() []filter.Option {
+	return nf.opts
 }
 
 func (nf *NodeFilter) IsExistReader(reader io.Reader, excludes ...rune) bool {
@@ -77,7 +133,7 @@ func (nf *NodeFilter) IsExistReader(reader io.Reader, excludes ...rune) bool {
 		if nf.checkExclude(ur, excludes...) {
 			continue
 		}
-		if (unicode.IsSpace(ur) || unicode.IsPunct(ur)) && len(uchars) > 0 {
+		if nf.IsSeparator(ur) && len(uchars) > 0 {
 			isExist, _ := nf.FindIn(string(uchars[:]))
 			if isExist {
 				return isExist
@@ -108,6 +164,69 @@ func (nf *NodeFilter) Remove(text ...string) {
 	nf.filter.DelWord(text...)
 }
 
+// Update 实现filter.Updater接口
+func (nf *NodeFilter) Update(added, removed []string) filter.SensitivewordFilter {
+	clone := &NodeFilter{
+		filter: nf.filter.Clone(),
+		opts:   nf.opts,
+	}
+	clone.filter.DelWord(removed...)
+	clone.filter.AddWord(added...)
+	return clone
+}
+
+// FilterStream 实现filter.Streamer接口
+func (nf *NodeFilter) FilterStream(ctx context.Context, reader io.Reader, fn func(filter.Match) bool) error {
+	return nf.filter.FilterStream(ctx, reader, fn)
+}
+
+// Compact 实现filter.Compactor接口
+func (nf *NodeFilter) Compact() {
+	nf.filter.Compact()
+}
+
+// Stats 实现filter.Compactor接口
+func (nf *NodeFilter) Stats() filter.Stats {
+	return nf.filter.Stats()
+}
+
+// MaxSpan 实现filter.Spanner接口，整词匹配或派生写法(如拼音)时还需之后的字符判断词尾是否为单词边界
+func (nf *NodeFilter) MaxSpan() int {
+	span := filter.SpanOf(nf.filter.Stats().Depth, nf.filter.MaxGap())
+	if nf.filter.WholeWord() || len(filter.NewOptions(nf.opts...).Expanders) > 0 {
+		span += filter.BoundaryContext
+	}
+	return span
+}
+
+// EncodeDict 实现filter.DictWriter接口，写入创建过滤器时的配置指纹及所有节点
+// 使用双数组时同时写入可以零拷贝加载的映像
+func (nf *NodeFilter) EncodeDict() *filter.DictEncoder {
+	e := filter.NewDictEncoder(filter.OptionsFingerprint(filter.NewOptions(nf.opts...)))
+	nf.filter.EncodeDict(e)
+	return e
+}
+
+// MarshalBinary 实现encoding.BinaryMarshaler接口
+// 将所有节点(包括词尾节点对应的敏感词及派生写法类型)编码为带版本号、配置指纹及校验和的二进制词典，
+// 需同时保存词条的附加信息时使用 filter.MetaFilter 的 MarshalBinary
+func (nf *NodeFilter) MarshalBinary() ([]byte, error) {
+	return nf.EncodeDict().Bytes(), nil
+}
+
+// UnmarshalBinary 实现encoding.BinaryUnmarshaler接口，以二进制词典中的节点替换过滤器的所有敏感词
+// 词典无效或生成词典时的配置与过滤器不同时返回error，过滤器保持不变；词典中词条的附加信息被忽略
+func (nf *NodeFilter) UnmarshalBinary(data []byte) error {
+	if nf.filter == nil {
+		*nf = *newNodeFilter()
+	}
+	d, err := filter.DecodeDict(data, filter.NewOptions(nf.opts...))
+	if err != nil {
+		return err
+	}
+	return nf.filter.DecodeDict(d)
+}
+
 func (nf *NodeFilter) addSensitiveWords(text string) {
 	nf.filter.AddWord(text)
 }
@@ -158,7 +277,7 @@ func (nf *NodeFilter) FilterReaderResult(reader io.Reader, excludes ...rune) (ma
 			continue
 		}
 
-		if (unicode.IsSpace(ur) || unicode.IsPunct(ur)) && len(uchars) > 0 {
+		if nf.IsSeparator(ur) && len(uchars) > 0 {
 			nf.filter.FindAllMap(string(uchars), data)
 			uchars = nil
 			continue
@@ -172,31 +291,33 @@ func (nf *NodeFilter) FilterReaderResult(reader io.Reader, excludes ...rune) (ma
 }
 
 func (nf *NodeFilter) Replace(text string, delim rune, excludes ...rune) (string, error) {
-	var newWchar []rune
+	// 替换所有命中(包括重叠的命中)，排除的字符保留在结果中
 	uchars := []rune(text)
-	for i, l := 0, len(uchars); i < l; i++ {
-		if nf.checkExclude(uchars[i], excludes...) {
-			continue
-		}
-		newWchar = append(newWchar, uchars[i])
+	for _, m := range nf.filter.FindMatches(text, filter.WithExcludes(excludes...)) {
+		m.Mask(uchars, delim)
 	}
-	newText := string(newWchar)
-	replaceText := nf.filter.Replace(newText, delim)
-	return replaceText, nil
+	return string(uchars), nil
+}
+
+// ReplaceWith 实现filter.Rewriter接口
+func (nf *NodeFilter) ReplaceWith(text string, r filter.Replacer, opts ...filter.MatchOption) (string, error) {
+	return filter.ReplaceMatches(text, nf.filter.FindMatches(text, opts...), r), nil
+}
+
+// FilterResultWith 实现filter.ResultFilter接口
+func (nf *NodeFilter) FilterResultWith(text string, opts ...filter.MatchOption) (map[string]int, error) {
+	return nf.filter.FindResult(text, opts...), nil
+}
+
+// FindMatches 查找文本中出现的所有敏感词及其在原文中的位置
+func (nf *NodeFilter) FindMatches(text string, opts ...filter.MatchOption) ([]filter.Match, error) {
+	return nf.filter.FindMatches(text, opts...), nil
 }
 
 // FindIn 检测敏感词
 func (nf *NodeFilter) FindIn(text string, excludes ...rune) (bool, string) {
-	var newWchar []rune
-	uchars := []rune(text)
-	for i, l := 0, len(uchars); i < l; i++ {
-		if nf.checkExclude(uchars[i], excludes...) {
-			continue
-		}
-		newWchar = append(newWchar, uchars[i])
-	}
-	newText := string(newWchar)
-	return nf.filter.FindIn(newText)
+	validated, first := nf.Validate(text, excludes...)
+	return !validated, first
 }
 
 // Validate 检测字符串是否合法
@@ -209,8 +330,14 @@ func (nf *NodeFilter) Validate(text string, excludes ...rune) (bool, string) {
 		}
 		newWchar = append(newWchar, uchars[i])
 	}
-	newText := string(newWchar)
-	return nf.filter.Validate(newText)
+	first, found := nf.filter.FindFirst(string(newWchar))
+	return !found, first
+}
+
+// IsSeparator 实现filter.Segmenter接口，是否为切分文本的分隔字符，允许间隔时不切分文本
+// 整词匹配时标点可能位于单词中间(如 "can't")，只在空白处切分；归一化为字母或数字的标点(如火星文)不切分文本
+func (nf *NodeFilter) IsSeparator(r rune) bool {
+	return nf.filter.MaxGap() == 0 && (unicode.IsSpace(r) || !nf.filter.WholeWord()) && filter.IsSeparator(nf.filter.Normalizer(), r)
 }
 
 func (nf *NodeFilter) checkExclude(u rune, excludes ...rune) bool {
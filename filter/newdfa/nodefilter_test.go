@@ -0,0 +1,20 @@
+package newdfa
+
+import (
+	"testing"
+
+	"github.com/hellobchain/sensitivewordfilter/filter"
+	"github.com/hellobchain/sensitivewordfilter/filter/filtertest"
+)
+
+func TestConformance(t *testing.T) {
+	filtertest.Run(t, func(words []string) filter.SensitivewordFilter {
+		return NewNodeFilter(words)
+	})
+}
+
+func TestConformanceDoubleArray(t *testing.T) {
+	filtertest.Run(t, func(words []string) filter.SensitivewordFilter {
+		return NewNodeFilter(words, filter.WithDoubleArray())
+	})
+}
@@ -0,0 +1,111 @@
+package filter
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+	"golang.org/x/text/width"
+)
+
+// Normalizer 字符归一化接口
+// 敏感词与待检测文本在匹配前都会经过相同的归一化处理
+type Normalizer interface {
+	// Normalize 将字符 r 归一化后的零个或多个字符追加到 dst 并返回
+	Normalize(dst []rune, r rune) []rune
+}
+
+// NormalizerFunc 函数形式的归一化
+type NormalizerFunc func(dst []rune, r rune) []rune
+
+// Normalize 实现Normalizer接口
+func (f NormalizerFunc) Normalize(dst []rune, r rune) []rune {
+	return f(dst, r)
+}
+
+var (
+	// NFKC Unicode兼容性分解后再组合，如 "①" 转换为 "1"，"ﬁ" 转换为 "fi"
+	NFKC Normalizer = NormalizerFunc(func(dst []rune, r rune) []rune {
+		if r < utf8.RuneSelf {
+			return append(dst, r)
+		}
+		return append(dst, []rune(norm.NFKC.String(string(r)))...)
+	})
+
+	// CaseFold 大小写折叠，统一转换为小写
+	CaseFold Normalizer = NormalizerFunc(func(dst []rune, r rune) []rune {
+		return append(dst, unicode.ToLower(r))
+	})
+
+	// WidthFold 全角半角折叠，如 "Ａ" 转换为 "A"，"ｶ" 转换为 "カ"
+	WidthFold Normalizer = NormalizerFunc(func(dst []rune, r rune) []rune {
+		if r < utf8.RuneSelf {
+			return append(dst, r)
+		}
+		return append(dst, []rune(width.Fold.String(string(r)))...)
+	})
+
+	// StripDiacritics 去除变音符号，如 "é" 转换为 "e"，单独出现的组合字符将被去除
+	StripDiacritics Normalizer = NormalizerFunc(func(dst []rune, r rune) []rune {
+		if r < utf8.RuneSelf {
+			return append(dst, r)
+		}
+		for _, d := range norm.NFD.String(string(r)) {
+			if !unicode.Is(unicode.Mn, d) {
+				dst = append(dst, d)
+			}
+		}
+		return dst
+	})
+)
+
+// Chain 按顺序组合多个归一化，忽略其中的nil
+func Chain(normalizers ...Normalizer) Normalizer {
+	var c chain
+	for _, n := range normalizers {
+		if n != nil {
+			c = append(c, n)
+		}
+	}
+	return c
+}
+
+type chain []Normalizer
+
+func (c chain) Normalize(dst []rune, r rune) []rune {
+	buf := []rune{r}
+	var next []rune
+	for _, n := range c {
+		next = next[:0]
+		for _, v := range buf {
+			next = n.Normalize(next, v)
+		}
+		buf, next = next, buf
+	}
+	return append(dst, buf...)
+}
+
+// NormalizeRune 使用归一化 n 处理字符 r 并追加到 dst，n 为nil时原样追加
+func NormalizeRune(n Normalizer, dst []rune, r rune) []rune {
+	if n == nil {
+		return append(dst, r)
+	}
+	return n.Normalize(dst, r)
+}
+
+// NormalizeString 使用归一化 n 处理字符串，n 为nil时原样返回
+func NormalizeString(n Normalizer, s string) string {
+	if n == nil {
+		return s
+	}
+	var dst []rune
+	for _, r := range s {
+		dst = n.Normalize(dst, r)
+	}
+	return string(dst)
+}
+
+// DefaultNormalizer 默认的归一化链：NFKC、全角半角折叠、大小写折叠及去除变音符号
+func DefaultNormalizer() Normalizer {
+	return Chain(NFKC, WidthFold, CaseFold, StripDiacritics)
+}
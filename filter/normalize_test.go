@@ -0,0 +1,58 @@
+package filter
+
+import "testing"
+
+func TestNormalizers(t *testing.T) {
+	testcases := []struct {
+		Name       string
+		Normalizer Normalizer
+		Text       string
+		Expect     string
+	}{
+		{"nfkc", NFKC, "①ﬁ㎏", "1fikg"},
+		{"case", CaseFold, "AbC", "abc"},
+		{"width", WidthFold, "ＡＢＣ１２３", "ABC123"},
+		{"diacritics", StripDiacritics, "café naïve", "cafe naive"},
+		{"combining", StripDiacritics, "café", "cafe"},
+		{"default", DefaultNormalizer(), "ＣＡＦÉ①", "cafe1"},
+		{"nil", nil, "ＡＢＣ", "ＡＢＣ"},
+	}
+
+	for _, tc := range testcases {
+		if got := NormalizeString(tc.Normalizer, tc.Text); got != tc.Expect {
+			t.Errorf("%s normalize %s, got %s, expect %s", tc.Name, tc.Text, got, tc.Expect)
+		}
+	}
+}
+
+func TestTextOffsets(t *testing.T) {
+	text := "ＢＡＤ ﬁx"
+	tx := NewText(text, NewMatchOptions(), nil, DefaultNormalizer())
+	if got := string(tx.Runes); got != "bad fix" {
+		t.Fatalf("normalized runes got %s, expect bad fix", got)
+	}
+	m := tx.Match(0, 3)
+	if m.Word != "ＢＡＤ" || m.Start != 0 || m.End != 3 || m.ByteStart != 0 || m.ByteEnd != 9 {
+		t.Errorf("match bad got %+v", m)
+	}
+	m = tx.Match(5, 7)
+	if m.Word != "ﬁx" || m.Start != 4 || m.End != 6 {
+		t.Errorf("match ix got %+v", m)
+	}
+}
+
+func TestNormalizerOptions(t *testing.T) {
+	// WithNormalizer 替换之前的设置，AppendNormalizer 在之前的设置之后组合
+	o := NewOptions(WithNormalizer(CaseFold), WithNormalizer(WidthFold))
+	if got := NormalizeString(o.Normalizer, "ＡB"); got != "AB" {
+		t.Errorf("WithNormalizer got %s, expect AB", got)
+	}
+	o = NewOptions(WithNormalizer(CaseFold), AppendNormalizer(WidthFold))
+	if got := NormalizeString(o.Normalizer, "ＡB"); got != "ab" {
+		t.Errorf("AppendNormalizer got %s, expect ab", got)
+	}
+	o = NewOptions(AppendNormalizer(CaseFold))
+	if got := NormalizeString(o.Normalizer, "AB"); got != "ab" {
+		t.Errorf("AppendNormalizer without previous normalizer got %s, expect ab", got)
+	}
+}
@@ -0,0 +1,116 @@
+package filter
+
+// Option 创建过滤器时的可选项
+type Option func(*Options)
+
+// Options 过滤器配置
+type Options struct {
+	// Normalizer 敏感词及待检测文本在匹配前的归一化处理
+	Normalizer Normalizer
+	// Expanders 加载敏感词时用于派生其他写法的扩展
+	Expanders []Expander
+	// MaxGap 敏感词相邻两个字符之间最多允许间隔的字符个数，0表示不允许间隔
+	MaxGap int
+	// GapClass 允许作为间隔的字符，nil表示任意字符都可以作为间隔
+	GapClass func(r rune) bool
+	// DoubleArray 使用双数组Trie存储敏感词
+	DoubleArray bool
+	// WholeWord 拉丁、西里尔等文字的敏感词只按整词匹配
+	WholeWord bool
+	// SurfaceText Filter、FilterResult 等返回原文中的命中文本，而不是归一化后的文本
+	SurfaceText bool
+}
+
+// Variant 敏感词的一种派生写法
+// 派生写法中拉丁、西里尔等文字的部分只按整词匹配(见 IsWholeWord)，避免拼音等短写法误伤正常的英文单词
+type Variant struct {
+	// Text 派生写法的文本
+	Text string
+	// Kind 派生写法的类型，如拼音、首字母等
+	Kind string
+}
+
+// Expander 从敏感词派生出其他写法(如拼音)的扩展
+type Expander interface {
+	// Expand 返回敏感词的派生写法，不包含敏感词本身
+	Expand(word string) []Variant
+}
+
+// Expand 返回敏感词及其所有派生写法，敏感词本身的类型为空字符串
+func (o *Options) Expand(word string) []Variant {
+	variants := []Variant{{Text: word}}
+	for _, e := range o.Expanders {
+		variants = append(variants, e.Expand(word)...)
+	}
+	return variants
+}
+
+// WithNormalizer 设置匹配前的归一化处理，多个归一化按顺序组合，多次设置时以最后一次为准
+func WithNormalizer(normalizers ...Normalizer) Option {
+	return func(o *Options) {
+		if len(normalizers) == 1 {
+			o.Normalizer = normalizers[0]
+			return
+		}
+		o.Normalizer = Chain(normalizers...)
+	}
+}
+
+// AppendNormalizer 在已设置的归一化处理之后增加归一化处理，供繁简体、拼音等可组合的可选项使用
+func AppendNormalizer(normalizers ...Normalizer) Option {
+	return func(o *Options) {
+		if o.Normalizer != nil {
+			normalizers = append([]Normalizer{o.Normalizer}, normalizers...)
+		}
+		WithNormalizer(normalizers...)(o)
+	}
+}
+
+// WithExpander 增加加载敏感词时派生其他写法的扩展
+func WithExpander(expanders ...Expander) Option {
+	return func(o *Options) {
+		o.Expanders = append(o.Expanders, expanders...)
+	}
+}
+
+// WithMaxGap 允许敏感词相邻两个字符之间间隔最多 n 个字符，如 "暴x力"、"暴。力"
+// class 限定可以作为间隔的字符(如 unicode.IsPunct)，为nil时任意字符都可以作为间隔
+// 开启后按空白及标点切分文本的过滤函数将不再切分文本
+func WithMaxGap(n int, class func(r rune) bool) Option {
+	return func(o *Options) {
+		o.MaxGap = n
+		o.GapClass = class
+	}
+}
+
+// WithDoubleArray 使用双数组Trie存储敏感词，查找结果不变，占用的内存更少，目前仅newdfa过滤器支持
+func WithDoubleArray() Option {
+	return func(o *Options) {
+		o.DoubleArray = true
+	}
+}
+
+// WithWholeWord 拉丁、西里尔等以空白分词的文字的敏感词只按整词匹配(如 "ass" 不会命中 "class")，
+// 单词边界按UAX #29的简化规则判断，汉字等文字的敏感词仍按子串匹配，目前仅newdfa过滤器支持
+func WithWholeWord() Option {
+	return func(o *Options) {
+		o.WholeWord = true
+	}
+}
+
+// WithSurfaceText Filter、FilterResult 等返回原文中的命中文本(不包括间隔及排除的字符)，而不是归一化后的文本，
+// 如繁简体等价匹配时返回文本中的 "臺獨" 而不是 "台独"；dfa、newdfa及规则过滤器支持
+func WithSurfaceText() Option {
+	return func(o *Options) {
+		o.SurfaceText = true
+	}
+}
+
+// NewOptions 根据可选项生成过滤器配置
+func NewOptions(opts ...Option) *Options {
+	o := new(Options)
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
@@ -0,0 +1,118 @@
+package filter
+
+import "sort"
+
+// Overlap 命中区间重叠时的处理方式
+type Overlap int
+
+const (
+	// OverlapAll 报告所有命中，包括重叠及被包含的命中
+	OverlapAll Overlap = iota
+	// OverlapLeftmostLongest 从左到右选择不重叠的命中，起始位置相同时选择最长的，长度相同时选择严重程度最高的
+	OverlapLeftmostLongest
+	// OverlapLeftmostFirst 从左到右选择不重叠的命中，起始位置相同时选择严重程度最高的，严重程度相同时选择最短的(最先命中的)
+	OverlapLeftmostFirst
+	// OverlapGreedy 按严重程度从高到低选择不与已选命中重叠的命中，严重程度相同时选择较长的，长度相同时选择靠左的
+	OverlapGreedy
+)
+
+// ResultFilter 支持按查找选项统计敏感词的过滤器
+type ResultFilter interface {
+	// FilterResultWith 按 opts 统计文本中出现的敏感词及出现次数，结果的键与 FilterResult 相同
+	// 与 FilterResult 不同，不在空白及标点处切分文本
+	FilterResultWith(text string, opts ...MatchOption) (map[string]int, error)
+}
+
+// WithOverlap 按 o 处理区间重叠的命中，默认为 OverlapAll
+func WithOverlap(o Overlap) MatchOption {
+	return func(opts *MatchOptions) {
+		opts.Overlap = o
+	}
+}
+
+// SelectOverlaps 按 o 从 matches 中选择命中，返回选中命中的下标，按命中位置排序
+// 严重程度取自命中词条的附加信息(Meta.Severity)，未设置时均为0
+func SelectOverlaps(matches []Match, o Overlap) []int {
+	idx := make([]int, len(matches))
+	for i := range idx {
+		idx[i] = i
+	}
+	byPosition := func(i, j int) bool {
+		a, b := matches[idx[i]], matches[idx[j]]
+		if a.Start != b.Start {
+			return a.Start < b.Start
+		}
+		return a.End < b.End
+	}
+	switch o {
+	case OverlapLeftmostLongest, OverlapLeftmostFirst:
+		sort.SliceStable(idx, func(i, j int) bool {
+			a, b := matches[idx[i]], matches[idx[j]]
+			if a.Start != b.Start {
+				return a.Start < b.Start
+			}
+			if o == OverlapLeftmostLongest && a.End != b.End {
+				return a.End > b.End
+			}
+			if a.Meta.Severity != b.Meta.Severity {
+				return a.Meta.Severity > b.Meta.Severity
+			}
+			return a.End < b.End
+		})
+		selected, end := idx[:0], 0
+		for _, i := range idx {
+			if matches[i].Start >= end {
+				selected = append(selected, i)
+				end = matches[i].End
+			}
+		}
+		return selected
+	case OverlapGreedy:
+		sort.SliceStable(idx, func(i, j int) bool {
+			a, b := matches[idx[i]], matches[idx[j]]
+			if a.Meta.Severity != b.Meta.Severity {
+				return a.Meta.Severity > b.Meta.Severity
+			}
+			if a.End-a.Start != b.End-b.Start {
+				return a.End-a.Start > b.End-b.Start
+			}
+			return a.Start < b.Start
+		})
+		var (
+			selected []int
+			used     []bool
+		)
+	next:
+		for _, i := range idx {
+			m := matches[i]
+			for len(used) < m.End {
+				used = append(used, false)
+			}
+			for p := m.Start; p < m.End; p++ {
+				if used[p] {
+					continue next
+				}
+			}
+			for p := m.Start; p < m.End; p++ {
+				used[p] = true
+			}
+			selected = append(selected, i)
+		}
+		idx = selected
+	}
+	sort.SliceStable(idx, byPosition)
+	return idx
+}
+
+// ResolveOverlaps 按 o 处理区间重叠的命中，OverlapAll 时原样返回，否则返回按命中位置排序的命中
+func ResolveOverlaps(matches []Match, o Overlap) []Match {
+	if o == OverlapAll || len(matches) == 0 {
+		return matches
+	}
+	idx := SelectOverlaps(matches, o)
+	result := make([]Match, len(idx))
+	for i, j := range idx {
+		result[i] = matches[j]
+	}
+	return result
+}
@@ -0,0 +1,70 @@
+package filter_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hellobchain/sensitivewordfilter/filter"
+	"github.com/hellobchain/sensitivewordfilter/filter/dfa"
+	"github.com/hellobchain/sensitivewordfilter/filter/newdfa"
+)
+
+func TestOverlapSeverity(t *testing.T) {
+	words := []string{"一个", "一个东西", "东西", "个东"}
+	metas := map[string]filter.Meta{
+		"一个东西": {Severity: 2},
+		"东西":   {Severity: 5},
+	}
+	testcases := []struct {
+		Overlap filter.Overlap
+		Expect  []string
+	}{
+		{filter.OverlapLeftmostLongest, []string{"一个东西"}},
+		// 起始位置相同时严重程度较高的优先
+		{filter.OverlapLeftmostFirst, []string{"一个东西"}},
+		// 严重程度最高的优先，之后选择不重叠的
+		{filter.OverlapGreedy, []string{"一个", "东西"}},
+	}
+	for name, f := range map[string]filter.SensitivewordFilter{
+		"dfa":    dfa.NewNodeFilter(words),
+		"newdfa": newdfa.NewNodeFilter(words),
+	} {
+		mf := filter.NewMetaFilter(f.(filter.MatchFilter), metas)
+		for _, tc := range testcases {
+			matches, err := mf.FindMatches("我有一个东西", filter.WithOverlap(tc.Overlap))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, m := range matches {
+				got = append(got, m.Entry)
+			}
+			if !reflect.DeepEqual(got, tc.Expect) {
+				t.Errorf("%s overlap %d: got %q, expect %q", name, tc.Overlap, got, tc.Expect)
+			}
+		}
+	}
+}
+
+func TestSelectOverlaps(t *testing.T) {
+	matches := []filter.Match{
+		{Entry: "a", Start: 0, End: 2},
+		{Entry: "b", Start: 1, End: 5},
+		{Entry: "c", Start: 4, End: 6},
+		{Entry: "d", Start: 6, End: 7},
+	}
+	testcases := []struct {
+		Overlap filter.Overlap
+		Expect  []int
+	}{
+		{filter.OverlapAll, []int{0, 1, 2, 3}},
+		{filter.OverlapLeftmostLongest, []int{0, 2, 3}},
+		{filter.OverlapLeftmostFirst, []int{0, 2, 3}},
+		{filter.OverlapGreedy, []int{1, 3}},
+	}
+	for _, tc := range testcases {
+		if got := filter.SelectOverlaps(matches, tc.Overlap); !reflect.DeepEqual(got, tc.Expect) {
+			t.Errorf("overlap %d: got %v, expect %v", tc.Overlap, got, tc.Expect)
+		}
+	}
+}
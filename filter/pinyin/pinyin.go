@@ -0,0 +1,137 @@
+// Package pinyin 从中文敏感词派生拼音写法，用于检测以拼音或拼音首字母规避过滤的文本
+//
+// 派生的拼音写法只按整词匹配(见 filter.Variant)，如 "暴力" 的首字母 "bl" 不会命中 "table"、"problem"
+// 多音字只取第一个读音，如 "银行" 派生为 "yinxing" 而不是 "yinhang"，其它读音的写法无法检测
+package pinyin
+
+import (
+	"strings"
+	"unicode"
+
+	gopinyin "github.com/mozillazg/go-pinyin"
+
+	"github.com/hellobchain/sensitivewordfilter/filter"
+)
+
+const (
+	// Full 全拼写法，如 "暴力" 派生为 "baoli"
+	Full = "pinyin"
+	// Initials 首字母写法，如 "暴力" 派生为 "bl"
+	Initials = "initials"
+	// Mixed 全拼与首字母混合写法，如 "暴力" 派生为 "baol"、"bli"
+	Mixed = "mixed"
+)
+
+const (
+	// MinHanLength 派生拼音写法要求敏感词至少包含的汉字个数
+	// 单个汉字的拼音过短，容易误伤正常的英文文本
+	MinHanLength = 2
+	// MaxMixedLength 派生混合写法的敏感词最多包含的汉字个数，超过时不派生混合写法
+	MaxMixedLength = 6
+)
+
+// NewExpander 创建拼音派生扩展，kinds 指定派生的写法类型，未指定时派生全部类型
+func NewExpander(kinds ...string) filter.Expander {
+	if len(kinds) == 0 {
+		kinds = []string{Full, Initials, Mixed}
+	}
+	e := &expander{}
+	for _, k := range kinds {
+		switch k {
+		case Full:
+			e.full = true
+		case Initials:
+			e.initials = true
+		case Mixed:
+			e.mixed = true
+		}
+	}
+	return e
+}
+
+// WithPinyin 开启拼音规避检测，加载敏感词时派生拼音写法并忽略大小写进行匹配
+// kinds 指定派生的写法类型，未指定时派生全部类型
+func WithPinyin(kinds ...string) filter.Option {
+	return func(o *filter.Options) {
+		filter.AppendNormalizer(filter.CaseFold)(o)
+		filter.WithExpander(NewExpander(kinds...))(o)
+	}
+}
+
+type expander struct {
+	full     bool
+	initials bool
+	mixed    bool
+}
+
+// syllable 敏感词中的一个字符及其拼音
+type syllable struct {
+	full    string
+	initial string
+	han     bool
+}
+
+// Expand 实现filter.Expander接口
+func (e *expander) Expand(word string) []filter.Variant {
+	var (
+		syllables []syllable
+		hans      int
+	)
+	args := gopinyin.NewArgs()
+	firstLetter := gopinyin.NewArgs()
+	firstLetter.Style = gopinyin.FirstLetter
+	for _, r := range word {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		full := gopinyin.SinglePinyin(r, args)
+		initial := gopinyin.SinglePinyin(r, firstLetter)
+		if len(full) == 0 || len(initial) == 0 {
+			s := string(unicode.ToLower(r))
+			syllables = append(syllables, syllable{full: s, initial: s})
+			continue
+		}
+		syllables = append(syllables, syllable{full: full[0], initial: initial[0], han: true})
+		hans++
+	}
+	if hans < MinHanLength {
+		return nil
+	}
+
+	var variants []filter.Variant
+	if e.full {
+		variants = append(variants, filter.Variant{Text: join(syllables, 0), Kind: Full})
+	}
+	if e.initials {
+		variants = append(variants, filter.Variant{Text: join(syllables, ^uint(0)), Kind: Initials})
+	}
+	if e.mixed && hans <= MaxMixedLength {
+		// 以二进制位表示每个汉字使用首字母(1)还是全拼(0)，排除全部全拼和全部首字母两种情况
+		all := uint(1)<<uint(hans) - 1
+		for mask := uint(1); mask < all; mask++ {
+			variants = append(variants, filter.Variant{Text: join(syllables, mask), Kind: Mixed})
+		}
+	}
+	return variants
+}
+
+// join 拼接拼音，mask 的第 i 位为1时第 i 个汉字使用首字母
+func join(syllables []syllable, mask uint) string {
+	var (
+		sb  strings.Builder
+		bit uint
+	)
+	for _, s := range syllables {
+		if !s.han {
+			sb.WriteString(s.full)
+			continue
+		}
+		if mask&(1<<bit) != 0 {
+			sb.WriteString(s.initial)
+		} else {
+			sb.WriteString(s.full)
+		}
+		bit++
+	}
+	return sb.String()
+}
@@ -0,0 +1,132 @@
+package pinyin
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hellobchain/sensitivewordfilter/filter"
+	"github.com/hellobchain/sensitivewordfilter/filter/dfa"
+	"github.com/hellobchain/sensitivewordfilter/filter/newdfa"
+)
+
+func TestExpand(t *testing.T) {
+	variants := NewExpander().Expand("暴力")
+	var got []string
+	for _, v := range variants {
+		got = append(got, v.Kind+":"+v.Text)
+	}
+	sort.Strings(got)
+	expect := []string{"initials:bl", "mixed:bli", "mixed:baol", "pinyin:baoli"}
+	sort.Strings(expect)
+	if !reflect.DeepEqual(expect, got) {
+		t.Errorf("expand 暴力, got %v, expect %v", got, expect)
+	}
+
+	if variants := NewExpander().Expand("力"); variants != nil {
+		t.Errorf("expand single han, got %v, expect nil", variants)
+	}
+}
+
+func TestWithPinyin(t *testing.T) {
+	filters := map[string]filter.SensitivewordFilter{
+		"dfa":    dfa.NewNodeFilter([]string{"暴力", "bl"}, WithPinyin()),
+		"newdfa": newdfa.NewNodeFilter([]string{"暴力", "bl"}, WithPinyin()),
+	}
+
+	for name, f := range filters {
+		matches, err := f.(filter.Matcher).FindMatches("不要BaoLi，不要bl，不要暴li")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 派生写法只按整词匹配，"BaoLi" 中的 "BaoL" 不再命中
+		expect := []filter.Match{
+			{Word: "BaoLi", Entry: "暴力", Variant: Full, Start: 2, End: 7, ByteStart: 6, ByteEnd: 11},
+			{Word: "bl", Entry: "bl", Start: 10, End: 12, ByteStart: 20, ByteEnd: 22},
+		}
+		if !reflect.DeepEqual(matches, expect) {
+			t.Errorf("%s find matches got %+v, expect %+v", name, matches, expect)
+		}
+
+		f.Remove("暴力")
+		if f.IsExist("baoli") {
+			t.Errorf("%s expect pinyin removed with its word", name)
+		}
+		if !f.IsExist("bl") {
+			t.Errorf("%s expect literal word kept", name)
+		}
+	}
+}
+
+// 敏感词与其他敏感词的派生写法路径相同时互不影响，删除时只删除属于自身的词尾
+func TestRemoveSharedPath(t *testing.T) {
+	filters := map[string]func(words ...string) filter.SensitivewordFilter{
+		"dfa": func(words ...string) filter.SensitivewordFilter {
+			return dfa.NewNodeFilter(words, WithPinyin())
+		},
+		"newdfa": func(words ...string) filter.SensitivewordFilter {
+			return newdfa.NewNodeFilter(words, WithPinyin())
+		},
+		"doublearray": func(words ...string) filter.SensitivewordFilter {
+			return newdfa.NewNodeFilter(words, WithPinyin(), filter.WithDoubleArray())
+		},
+	}
+
+	for name, newFilter := range filters {
+		f := newFilter("微信")
+		f.Remove("weixin")
+		if !f.IsExist("weixin") {
+			t.Errorf("%s expect pinyin of 微信 kept after removing literal weixin", name)
+		}
+
+		f = newFilter("weixin", "微信")
+		f.Remove("微信")
+		matches, err := f.(filter.Matcher).FindMatches("weixin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(matches) != 1 || matches[0].Entry != "weixin" {
+			t.Errorf("%s expect literal weixin kept after removing 微信, got %+v", name, matches)
+		}
+	}
+}
+
+func TestPinyinWordBoundary(t *testing.T) {
+	const text = "this table is a problem, bl"
+	for name, f := range map[string]filter.SensitivewordFilter{
+		"dfa":         dfa.NewNodeFilter([]string{"暴力"}, WithPinyin()),
+		"newdfa":      newdfa.NewNodeFilter([]string{"暴力"}, WithPinyin()),
+		"doublearray": newdfa.NewNodeFilter([]string{"暴力"}, WithPinyin(), filter.WithDoubleArray()),
+	} {
+		result, err := f.FilterResult(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expect := map[string]int{"bl": 1}; !reflect.DeepEqual(result, expect) {
+			t.Errorf("%s filter result got %v, expect %v", name, result, expect)
+		}
+		if replaced, _ := f.Replace(text, '*'); replaced != "this table is a problem, **" {
+			t.Errorf("%s replace got %q", name, replaced)
+		}
+		if !f.IsExist("说baoli好") || f.IsExist("xbaolix") {
+			t.Errorf("%s expect pinyin matched only as a whole word", name)
+		}
+		var words []string
+		err = f.(filter.Streamer).FilterStream(context.Background(), strings.NewReader(text), func(m filter.Match) bool {
+			words = append(words, m.Word)
+			return true
+		})
+		if err != nil || !reflect.DeepEqual(words, []string{"bl"}) {
+			t.Errorf("%s filter stream got %v %v", name, words, err)
+		}
+	}
+}
+
+func TestWithoutPinyin(t *testing.T) {
+	f := newdfa.NewNodeFilter([]string{"暴力"})
+	if f.IsExist("baoli") {
+		t.Errorf("expect no pinyin match when mode is off")
+	}
+}
@@ -0,0 +1,129 @@
+package filter
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Replacer 敏感词的替换策略
+type Replacer interface {
+	// Replace 返回命中 m 在原文中替换后的文本
+	Replace(m Match) string
+}
+
+// ReplacerFunc 函数形式的替换策略
+type ReplacerFunc func(m Match) string
+
+// Replace 实现Replacer接口
+func (f ReplacerFunc) Replace(m Match) string {
+	return f(m)
+}
+
+// Rewriter 支持替换策略的过滤器
+type Rewriter interface {
+	// ReplaceWith 使用替换策略 r 替换文本中的敏感词，opts 中的重叠处理方式决定替换哪些命中
+	// 重叠的命中无法同时替换，OverlapAll 时按 OverlapLeftmostLongest 处理，MaskReplacer 除外
+	ReplaceWith(text string, r Replacer, opts ...MatchOption) (string, error)
+}
+
+// MaskReplacer 使用 delim 替换命中的每个字符，间隔字符保持不变
+// 逐字符替换不改变文本长度，ReplaceMatches 会替换所有命中(包括重叠的命中)的字符，与 Replace 的结果相同
+func MaskReplacer(delim rune) Replacer {
+	return maskReplacer(delim)
+}
+
+type maskReplacer rune
+
+// Replace 实现Replacer接口
+func (r maskReplacer) Replace(m Match) string {
+	return KeepEndsReplacer(rune(r), 0, 0).Replace(m)
+}
+
+// FixedReplacer 无论命中多长都替换为 s，如 "***"
+func FixedReplacer(s string) Replacer {
+	return ReplacerFunc(func(Match) string {
+		return s
+	})
+}
+
+// KeepEndsReplacer 保留命中开头 head 个及末尾 tail 个字符，其余字符使用 delim 替换，如 "暴*"
+// 命中过短时优先保留开头的字符，且至少替换一个字符
+func KeepEndsReplacer(delim rune, head, tail int) Replacer {
+	return ReplacerFunc(func(m Match) string {
+		n, h, t := utf8.RuneCountInString(m.Word), head, tail
+		if h+t >= n {
+			t = 0
+			if h >= n {
+				h = n - 1
+			}
+		}
+		var b strings.Builder
+		gaps := m.Gaps
+		i := 0
+		for _, c := range m.Word {
+			switch {
+			case len(gaps) > 0 && gaps[0] == m.Start+i:
+				gaps = gaps[1:]
+			case i >= h && i < n-t:
+				c = delim
+			}
+			b.WriteRune(c)
+			i++
+		}
+		return b.String()
+	})
+}
+
+// WordReplacer 按命中的字典词条替换为 words 中对应的文本，如 "damn" 替换为 "darn"
+// 词条不在 words 中时使用 fallback，fallback 为nil时使用 '*' 替换命中的每个字符
+func WordReplacer(words map[string]string, fallback Replacer) Replacer {
+	return keyReplacer(words, fallback, func(m Match) string {
+		return m.Entry
+	})
+}
+
+// CategoryReplacer 按命中词条的分类替换为 tokens 中对应的文本，如广告类替换为 "[广告]"
+// 分类不在 tokens 中时使用 fallback，fallback 为nil时使用 '*' 替换命中的每个字符
+// 过滤器需经过 MetaFilter 包装，命中才会携带分类
+func CategoryReplacer(tokens map[string]string, fallback Replacer) Replacer {
+	return keyReplacer(tokens, fallback, func(m Match) string {
+		return m.Meta.Category
+	})
+}
+
+func keyReplacer(values map[string]string, fallback Replacer, key func(Match) string) Replacer {
+	if fallback == nil {
+		fallback = MaskReplacer('*')
+	}
+	return ReplacerFunc(func(m Match) string {
+		if v, ok := values[key(m)]; ok {
+			return v
+		}
+		return fallback.Replace(m)
+	})
+}
+
+// ReplaceMatches 使用替换策略 r 替换 text 中的命中，matches 为 text 中查找到的命中
+// 仍有区间重叠的命中时按 OverlapLeftmostLongest 选择替换的命中，r 为 MaskReplacer 时替换所有命中
+func ReplaceMatches(text string, matches []Match, r Replacer) string {
+	if len(matches) == 0 {
+		return text
+	}
+	if delim, ok := r.(maskReplacer); ok {
+		uchars := []rune(text)
+		for _, m := range matches {
+			m.Mask(uchars, rune(delim))
+		}
+		return string(uchars)
+	}
+	selected := ResolveOverlaps(matches, OverlapLeftmostLongest)
+	var b strings.Builder
+	last := 0
+	for _, m := range selected {
+		b.WriteString(text[last:m.ByteStart])
+		b.WriteString(r.Replace(m))
+		last = m.ByteEnd
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
@@ -0,0 +1,65 @@
+package filter_test
+
+import (
+	"testing"
+
+	"github.com/hellobchain/sensitivewordfilter/filter"
+	"github.com/hellobchain/sensitivewordfilter/filter/dfa"
+	"github.com/hellobchain/sensitivewordfilter/filter/newdfa"
+)
+
+func TestReplaceWith(t *testing.T) {
+	const text = "暴力和damn，赌博机广告"
+	words := []string{"暴力", "damn", "赌博", "赌博机", "广告"}
+	testcases := []struct {
+		Name     string
+		Replacer filter.Replacer
+		Expect   string
+	}{
+		{"mask", filter.MaskReplacer('*'), "**和****，*****"},
+		{"fixed", filter.FixedReplacer("***"), "***和***，******"},
+		{"keepends", filter.KeepEndsReplacer('*', 1, 1), "暴*和d**n，赌*机广*"},
+		{"word", filter.WordReplacer(map[string]string{"damn": "darn"}, filter.FixedReplacer("***")), "***和darn，******"},
+		{"category", filter.CategoryReplacer(map[string]string{"ads": "[广告]"}, nil), "**和****，***[广告]"},
+		{"func", filter.ReplacerFunc(func(m filter.Match) string { return "<" + m.Entry + ">" }), "<暴力>和<damn>，<赌博机><广告>"},
+	}
+	for name, f := range map[string]filter.SensitivewordFilter{
+		"dfa":    dfa.NewNodeFilter(words),
+		"newdfa": newdfa.NewNodeFilter(words),
+	} {
+		mf := filter.NewMetaFilter(f.(filter.MatchFilter), map[string]filter.Meta{"广告": {Category: "ads"}})
+		for _, tc := range testcases {
+			got, err := mf.ReplaceWith(text, tc.Replacer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.Expect {
+				t.Errorf("%s %s: got %q, expect %q", name, tc.Name, got, tc.Expect)
+			}
+		}
+		got, _ := f.(filter.Rewriter).ReplaceWith(text, filter.MaskReplacer('*'))
+		if expect, _ := f.Replace(text, '*'); got != expect {
+			t.Errorf("%s: mask got %q, expect same as Replace %q", name, got, expect)
+		}
+	}
+}
+
+func TestMaskReplacerOverlap(t *testing.T) {
+	words := []string{"一个东西", "东西五", "ab", "abcd", "cde"}
+	for name, f := range map[string]filter.SensitivewordFilter{
+		"dfa":    dfa.NewNodeFilter(words),
+		"newdfa": newdfa.NewNodeFilter(words),
+	} {
+		// 首尾相接的重叠命中都被替换，与 Replace 的结果相同
+		for text, expect := range map[string]string{"一个东西五": "*****", "abcde": "*****"} {
+			got, err := f.(filter.Rewriter).ReplaceWith(text, filter.MaskReplacer('*'))
+			if err != nil {
+				t.Fatal(err)
+			}
+			replaced, _ := f.Replace(text, '*')
+			if got != expect || replaced != expect {
+				t.Errorf("%s %q: mask got %q, Replace got %q, expect %q", name, text, got, replaced, expect)
+			}
+		}
+	}
+}
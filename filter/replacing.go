@@ -0,0 +1,276 @@
+package filter
+
+import (
+	"bytes"
+	"io"
+	"unicode/utf8"
+)
+
+const (
+	// defaultLookahead 过滤器未实现Spanner接口时保留的字符个数
+	defaultLookahead = 64
+	// replacingChunk 替换读取流每次从底层读取的字节数
+	replacingChunk = 4096
+)
+
+// Spanner 可报告一个命中最多跨越多少个字符的过滤器
+type Spanner interface {
+	// MaxSpan 返回一个命中最多跨越的字符个数，按归一化后的字符计算，不含排除的字符
+	MaxSpan() int
+}
+
+// SpanOf 最长的敏感词为 depth 个字符、相邻两个字符之间最多间隔 maxGap 个字符时，一个命中最多跨越的字符个数
+func SpanOf(depth, maxGap int) int {
+	if depth == 0 {
+		return 0
+	}
+	return depth + (depth-1)*maxGap
+}
+
+// ReplaceOption 流式替换敏感词时的可选项
+type ReplaceOption func(*ReplaceOptions)
+
+// ReplaceOptions 流式替换敏感词时的配置
+type ReplaceOptions struct {
+	// Delim 替换敏感词的字符，默认为 '*'
+	Delim rune
+	// Excludes 匹配时跳过的字符
+	Excludes []rune
+	// Lookahead 一个命中最多跨越的字符个数，输出时保留其减一个字符以便与之后的文本一起匹配
+	// 为0时使用过滤器报告的值(Spanner)，在创建时确定，之后增加的更长的敏感词可能无法跨越写入边界命中
+	Lookahead int
+}
+
+// WithDelim 使用 delim 替换敏感词
+func WithDelim(delim rune) ReplaceOption {
+	return func(o *ReplaceOptions) {
+		o.Delim = delim
+	}
+}
+
+// WithReplaceExcludes 匹配时跳过指定的字符
+func WithReplaceExcludes(excludes ...rune) ReplaceOption {
+	return func(o *ReplaceOptions) {
+		o.Excludes = append(o.Excludes, excludes...)
+	}
+}
+
+// WithLookahead 指定一个命中最多跨越的字符个数
+func WithLookahead(n int) ReplaceOption {
+	return func(o *ReplaceOptions) {
+		o.Lookahead = n
+	}
+}
+
+// NewReplaceOptions 根据可选项生成配置
+func NewReplaceOptions(opts ...ReplaceOption) *ReplaceOptions {
+	o := &ReplaceOptions{Delim: '*'}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// streamReplacer 流式替换敏感词，保留末尾可能与之后的文本组成敏感词的字符
+type streamReplacer struct {
+	f     SensitivewordFilter
+	delim rune
+	// excludes 匹配时跳过的字符，不计入保留的字符个数
+	excludes *MatchOptions
+	hold     int
+	// normalizer 过滤器的归一化，保留的字符按归一化后的字符个数计算，如去除的组合字符不计入
+	normalizer Normalizer
+	buf        []rune
+
+	// raw 尚未解码的不完整UTF-8字节
+	raw []byte
+	// runes, masked 尚未输出的字符及其是否已被替换，前 ctx 个字符已输出，只用于判断单词边界
+	runes  []rune
+	masked []bool
+	ctx    int
+	out    bytes.Buffer
+}
+
+func newStreamReplacer(f SensitivewordFilter, opts []ReplaceOption) *streamReplacer {
+	o := NewReplaceOptions(opts...)
+	lookahead := o.Lookahead
+	if lookahead <= 0 {
+		lookahead = defaultLookahead
+		if s, ok := f.(Spanner); ok {
+			lookahead = s.MaxSpan()
+		}
+	}
+	r := &streamReplacer{
+		f:          f,
+		delim:      o.Delim,
+		excludes:   NewMatchOptions(WithExcludes(o.Excludes...)),
+		normalizer: optionsOf(f).Normalizer,
+	}
+	if lookahead > 1 {
+		r.hold = lookahead - 1
+	}
+	return r
+}
+
+// write 解码 p 中完整的字符，final 为true时不完整的字节也按无效字符解码
+func (r *streamReplacer) write(p []byte, final bool) {
+	r.raw = append(r.raw, p...)
+	i := 0
+	for i < len(r.raw) && (final || utf8.FullRune(r.raw[i:])) {
+		c, size := utf8.DecodeRune(r.raw[i:])
+		r.runes = append(r.runes, c)
+		r.masked = append(r.masked, false)
+		i += size
+	}
+	r.raw = r.raw[:copy(r.raw, r.raw[i:])]
+}
+
+// flush 替换敏感词并返回可以输出的文本，final 为false时保留末尾归一化后为 hold 个参与匹配的字符
+// 返回的字节在下次调用前有效
+func (r *streamReplacer) flush(final bool) ([]byte, error) {
+	n := len(r.runes)
+	for hold := r.hold; !final && hold > 0 && n > r.ctx; {
+		if n--; !r.excludes.IsExclude(r.runes[n]) {
+			r.buf = NormalizeRune(r.normalizer, r.buf[:0], r.runes[n])
+			hold -= len(r.buf)
+		}
+	}
+	r.out.Reset()
+	if n <= r.ctx {
+		return nil, nil
+	}
+	if err := r.mask(); err != nil {
+		return nil, err
+	}
+	for i := r.ctx; i < n; i++ {
+		if r.masked[i] {
+			r.out.WriteRune(r.delim)
+		} else {
+			r.out.WriteRune(r.runes[i])
+		}
+	}
+	keep := n - BoundaryContext
+	if keep < 0 {
+		keep = 0
+	}
+	r.ctx = n - keep
+	r.runes = r.runes[:copy(r.runes, r.runes[keep:])]
+	r.masked = r.masked[:copy(r.masked, r.masked[keep:])]
+	return r.out.Bytes(), nil
+}
+
+// mask 标记尚未输出的字符中被替换的字符
+// 起始于已输出字符的命中已在之前替换，不再标记，以免缺少之前的字符时误判单词边界
+func (r *streamReplacer) mask() error {
+	text := string(r.runes)
+	if m, ok := r.f.(Matcher); ok {
+		matches, err := m.FindMatches(text, WithExcludes(r.excludes.Excludes...), WithOverlap(OverlapAll))
+		if err != nil {
+			return err
+		}
+		for _, match := range matches {
+			if match.Start < r.ctx {
+				continue
+			}
+			gaps := match.Gaps
+			for i := match.Start; i < match.End; i++ {
+				if len(gaps) > 0 && gaps[0] == i {
+					gaps = gaps[1:]
+					continue
+				}
+				r.masked[i] = true
+			}
+		}
+		return nil
+	}
+	replaced, err := r.f.Replace(text, r.delim, r.excludes.Excludes...)
+	if err != nil {
+		return err
+	}
+	// 保留的字符可能已被之前的命中替换，与本次的结果合并
+	if out := []rune(replaced); len(out) == len(r.runes) {
+		for i, c := range out {
+			if c != r.runes[i] {
+				r.masked[i] = true
+			}
+		}
+	}
+	return nil
+}
+
+// NewReplacingReader 创建替换敏感词的读取流，从 rd 读取的文本中的敏感词使用 Replace 替换后返回
+// 只保留一个命中最多跨越的字符个数，跨越多次读取的敏感词同样会被替换
+func NewReplacingReader(rd io.Reader, f SensitivewordFilter, opts ...ReplaceOption) *ReplacingReader {
+	return &ReplacingReader{
+		rd:    rd,
+		r:     newStreamReplacer(f, opts),
+		chunk: make([]byte, replacingChunk),
+	}
+}
+
+// ReplacingReader 替换敏感词的读取流
+type ReplacingReader struct {
+	rd    io.Reader
+	r     *streamReplacer
+	chunk []byte
+	out   []byte
+	err   error
+}
+
+// Read 实现io.Reader接口，底层读取出错时不再输出保留的字符
+func (rr *ReplacingReader) Read(p []byte) (int, error) {
+	for len(rr.out) == 0 {
+		if rr.err != nil {
+			return 0, rr.err
+		}
+		n, err := rr.rd.Read(rr.chunk)
+		rr.r.write(rr.chunk[:n], err == io.EOF)
+		out, ferr := rr.r.flush(err == io.EOF)
+		if ferr != nil {
+			err = ferr
+		}
+		rr.out, rr.err = out, err
+	}
+	n := copy(p, rr.out)
+	rr.out = rr.out[n:]
+	return n, nil
+}
+
+// NewReplacingWriter 创建替换敏感词的写入流，写入的文本中的敏感词使用 Replace 替换后写入 w
+// 只保留一个命中最多跨越的字符个数，跨越多次写入的敏感词同样会被替换，写入完毕后需调用 Close 输出保留的字符
+func NewReplacingWriter(w io.Writer, f SensitivewordFilter, opts ...ReplaceOption) *ReplacingWriter {
+	return &ReplacingWriter{
+		w: w,
+		r: newStreamReplacer(f, opts),
+	}
+}
+
+// ReplacingWriter 替换敏感词的写入流
+type ReplacingWriter struct {
+	w io.Writer
+	r *streamReplacer
+}
+
+// Write 实现io.Writer接口
+func (rw *ReplacingWriter) Write(p []byte) (int, error) {
+	rw.r.write(p, false)
+	if err := rw.flush(false); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close 输出保留的字符，不会关闭 w
+func (rw *ReplacingWriter) Close() error {
+	rw.r.write(nil, true)
+	return rw.flush(true)
+}
+
+func (rw *ReplacingWriter) flush(final bool) error {
+	out, err := rw.r.flush(final)
+	if err != nil || len(out) == 0 {
+		return err
+	}
+	_, err = rw.w.Write(out)
+	return err
+}
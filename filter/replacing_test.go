@@ -0,0 +1,94 @@
+package filter_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/hellobchain/sensitivewordfilter/filter"
+	"github.com/hellobchain/sensitivewordfilter/filter/ac"
+	"github.com/hellobchain/sensitivewordfilter/filter/dfa"
+	"github.com/hellobchain/sensitivewordfilter/filter/newdfa"
+)
+
+func TestReplacingReaderWriter(t *testing.T) {
+	const (
+		text   = "日志：这是暴力内容，广告位招租，联系方式见下"
+		expect = "日志：这是**内容，***招租，联系方式见下"
+	)
+	words := []string{"暴力", "广告位", "告"}
+	filters := map[string]filter.SensitivewordFilter{
+		"dfa":    dfa.NewNodeFilter(words),
+		"newdfa": newdfa.NewNodeFilter(words),
+		"ac":     ac.NewNodeFilter(words),
+	}
+	for name, f := range filters {
+		if span := f.(filter.Spanner).MaxSpan(); span != 3 {
+			t.Errorf("%s: expect max span 3, got %d", name, span)
+		}
+
+		data, err := ioutil.ReadAll(filter.NewReplacingReader(iotest.OneByteReader(strings.NewReader(text)), f))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != expect {
+			t.Errorf("%s: reader got %q, expect %q", name, data, expect)
+		}
+
+		var buf bytes.Buffer
+		w := filter.NewReplacingWriter(&buf, f, filter.WithDelim('#'))
+		for _, c := range []byte(text) {
+			if _, err := w.Write([]byte{c}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// 只保留可能组成敏感词的最后两个字符
+		if got := strings.TrimPrefix(strings.ReplaceAll(expect, "*", "#"), buf.String()); got != "见下" {
+			t.Errorf("%s: expect only last 2 runes held, got %q", name, got)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != strings.ReplaceAll(expect, "*", "#") {
+			t.Errorf("%s: writer got %q", name, got)
+		}
+	}
+}
+
+func TestReplacingDecomposed(t *testing.T) {
+	// 分解形式的变音符号在归一化时被去除，命中在原文中跨越的字符多于归一化后的字符
+	const text = "un nai\u0308ve cafe\u0301 et nai\u0308ve"
+	for name, opts := range map[string][]filter.Option{
+		"trie":        {filter.WithNormalizer(filter.StripDiacritics)},
+		"doublearray": {filter.WithNormalizer(filter.StripDiacritics), filter.WithDoubleArray()},
+	} {
+		f := newdfa.NewNodeFilter([]string{"naive", "cafe"}, opts...)
+		expect, err := f.Replace(text, '*')
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		w := filter.NewReplacingWriter(&buf, f)
+		// 逐字符写入，组合字符与其前后的字符分别写入
+		for _, c := range text {
+			if _, err := w.Write([]byte(string(c))); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != expect {
+			t.Errorf("%s: writer got %q, expect %q", name, buf.String(), expect)
+		}
+		data, err := ioutil.ReadAll(filter.NewReplacingReader(iotest.OneByteReader(strings.NewReader(text)), f))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != expect {
+			t.Errorf("%s: reader got %q, expect %q", name, data, expect)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package rule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	// MaxWildcard 通配符 "*" 最多匹配的字符个数
+	MaxWildcard = 8
+	// MaxRepeat 重复次数 "{n,m}" 允许的最大值
+	MaxRepeat = 64
+)
+
+// Pattern 编译后的规则
+// 规则由若干片段组成，每个片段匹配连续若干个属于同一字符类的字符
+type Pattern struct {
+	source string
+	pieces []piece
+}
+
+// String 规则原文
+func (p *Pattern) String() string {
+	return p.source
+}
+
+// piece 规则的一个片段，匹配 min 到 max 个属于字符类的字符
+type piece struct {
+	key   string
+	class charClass
+	min   int
+	max   int
+}
+
+// literal 片段是否为单个字面字符
+func (p piece) literal() (rune, bool) {
+	if p.min == 1 && p.max == 1 && p.class.lit != 0 {
+		return p.class.lit, true
+	}
+	return 0, false
+}
+
+// charClass 字符类
+type charClass struct {
+	lit    rune
+	any    bool
+	negate bool
+	ranges []runeRange
+	funcs  []func(rune) bool
+}
+
+type runeRange struct {
+	lo, hi rune
+}
+
+func (c charClass) match(r rune) bool {
+	if c.lit != 0 {
+		return r == c.lit
+	}
+	if c.any {
+		return true
+	}
+	matched := false
+	for _, rg := range c.ranges {
+		if rg.lo <= r && r <= rg.hi {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		for _, f := range c.funcs {
+			if f(r) {
+				matched = true
+				break
+			}
+		}
+	}
+	return matched != c.negate
+}
+
+// Compile 编译规则
+//
+// 规则语法：
+//
+//	?         任意一个字符，如 "fu?k"
+//	*         任意 0 到 MaxWildcard 个字符，如 "赌*场"
+//	[...]     字符集合，支持范围及取反，如 "[微威]信"、"[a-z]"、"[^0-9]"
+//	\d \w \s  数字、文字(字母、数字及下划线)、空白字符，如 "\d{11}"
+//	{n} {n,m} 前一个字符或字符类重复 n 次或 n 到 m 次
+//	\         转义下一个字符，如 "\*"、"\?"、"\["
+//
+// 其余字符按字面匹配
+func Compile(rule string) (*Pattern, error) {
+	runes := []rune(rule)
+	p := &Pattern{source: rule}
+	for i := 0; i < len(runes); {
+		start := i
+		var pc piece
+		switch r := runes[i]; r {
+		case '*':
+			pc = piece{class: charClass{any: true}, min: 0, max: MaxWildcard}
+			i++
+		case '?':
+			pc = piece{class: charClass{any: true}, min: 1, max: 1}
+			i++
+		case '[':
+			class, next, err := parseSet(runes, i+1)
+			if err != nil {
+				return nil, fmt.Errorf("rule: %q: %v", rule, err)
+			}
+			pc = piece{class: class, min: 1, max: 1}
+			i = next
+		case '\\':
+			if i+1 >= len(runes) {
+				return nil, fmt.Errorf("rule: %q: 末尾的转义字符", rule)
+			}
+			pc = piece{class: escape(runes[i+1]), min: 1, max: 1}
+			i += 2
+		case '{', '}', ']':
+			return nil, fmt.Errorf("rule: %q: 位置%d的字符 %q 需要转义", rule, i, r)
+		default:
+			pc = piece{class: charClass{lit: r}, min: 1, max: 1}
+			i++
+		}
+		if i < len(runes) && runes[i] == '{' {
+			if pc.max != 1 || pc.min != 1 {
+				return nil, fmt.Errorf("rule: %q: 位置%d的重复次数没有可重复的字符", rule, i)
+			}
+			min, max, next, err := parseRepeat(runes, i+1)
+			if err != nil {
+				return nil, fmt.Errorf("rule: %q: %v", rule, err)
+			}
+			pc.min, pc.max = min, max
+			i = next
+		}
+		pc.key = string(runes[start:i])
+		p.pieces = append(p.pieces, pc)
+	}
+	if len(p.pieces) == 0 {
+		return nil, fmt.Errorf("rule: 规则为空")
+	}
+	minLen := 0
+	for _, pc := range p.pieces {
+		minLen += pc.min
+	}
+	if minLen == 0 {
+		return nil, fmt.Errorf("rule: %q: 规则可以匹配空文本", rule)
+	}
+	return p, nil
+}
+
+// MustCompile 编译规则，规则错误时panic
+func MustCompile(rule string) *Pattern {
+	p, err := Compile(rule)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+func escape(r rune) charClass {
+	switch r {
+	case 'd':
+		return charClass{funcs: []func(rune) bool{unicode.IsDigit}}
+	case 'w':
+		return charClass{funcs: []func(rune) bool{isWord}}
+	case 's':
+		return charClass{funcs: []func(rune) bool{unicode.IsSpace}}
+	}
+	return charClass{lit: r}
+}
+
+func isWord(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// parseSet 解析 "[" 之后的字符集合，返回 "]" 之后的位置
+func parseSet(runes []rune, i int) (charClass, int, error) {
+	var class charClass
+	if i < len(runes) && runes[i] == '^' {
+		class.negate = true
+		i++
+	}
+	first := true
+	for i < len(runes) {
+		r := runes[i]
+		if r == ']' && !first {
+			if len(class.ranges) == 0 && len(class.funcs) == 0 {
+				return class, 0, fmt.Errorf("字符集合为空")
+			}
+			return class, i + 1, nil
+		}
+		first = false
+		if r == '\\' {
+			if i+1 >= len(runes) {
+				break
+			}
+			esc := escape(runes[i+1])
+			i += 2
+			if esc.lit == 0 {
+				class.funcs = append(class.funcs, esc.funcs...)
+				continue
+			}
+			r = esc.lit
+		} else {
+			i++
+		}
+		lo, hi := r, r
+		if i+1 < len(runes) && runes[i] == '-' && runes[i+1] != ']' {
+			hi = runes[i+1]
+			if hi == '\\' && i+2 < len(runes) {
+				hi = runes[i+2]
+				i++
+			}
+			i += 2
+			if hi < lo {
+				return class, 0, fmt.Errorf("字符范围 %q-%q 无效", lo, hi)
+			}
+		}
+		class.ranges = append(class.ranges, runeRange{lo: lo, hi: hi})
+	}
+	return class, 0, fmt.Errorf("字符集合缺少 \"]\"")
+}
+
+// parseRepeat 解析 "{" 之后的重复次数，返回 "}" 之后的位置
+func parseRepeat(runes []rune, i int) (int, int, int, error) {
+	end := i
+	for end < len(runes) && runes[end] != '}' {
+		end++
+	}
+	if end >= len(runes) {
+		return 0, 0, 0, fmt.Errorf("重复次数缺少 \"}\"")
+	}
+	parts := strings.SplitN(string(runes[i:end]), ",", 2)
+	min, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("重复次数 %q 无效", string(runes[i:end]))
+	}
+	max := min
+	if len(parts) == 2 {
+		if max, err = strconv.Atoi(strings.TrimSpace(parts[1])); err != nil {
+			return 0, 0, 0, fmt.Errorf("重复次数 %q 无效", string(runes[i:end]))
+		}
+	}
+	if min < 0 || max < min || max > MaxRepeat {
+		return 0, 0, 0, fmt.Errorf("重复次数 %q 超出范围", string(runes[i:end]))
+	}
+	return min, max, end + 1, nil
+}
@@ -0,0 +1,407 @@
+// Package rule 提供基于规则的敏感词过滤，支持通配符、字符集合及重复次数，
+// 规则被编译为与字面敏感词共享前缀的自动机
+//
+// 可选项中仅支持归一化(filter.WithNormalizer)、整词匹配(filter.WithWholeWord)及返回原文(filter.WithSurfaceText)，
+// 派生写法(Expanders)及间隔(MaxGap、GapClass)将被忽略，间隔可在规则中以通配符表示，如 "赌*场"
+package rule
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/hellobchain/sensitivewordfilter/filter"
+)
+
+// NewNodeReaderFilter 创建规则过滤器，实现敏感词的过滤
+// 从可读流中读取规则数据(以指定的分隔符读取数据)，无效的规则将被忽略
+func NewNodeReaderFilter(rd io.Reader, delim byte, opts ...filter.Option) filter.SensitivewordFilter {
+	nf := newNodeFilter(opts...)
+	buf := new(bytes.Buffer)
+	_, _ = io.Copy(buf, rd)
+	buf.WriteByte(delim)
+	for {
+		line, err := buf.ReadString(delim)
+		if err != nil {
+			break
+		}
+		if line == "" {
+			continue
+		}
+		_ = nf.AddRules(line)
+	}
+	buf.Reset()
+	return nf
+}
+
+// NewNodeChanFilter 创建规则过滤器，实现敏感词的过滤
+// 从通道中读取规则数据，无效的规则将被忽略
+func NewNodeChanFilter(text <-chan string, opts ...filter.Option) filter.SensitivewordFilter {
+	nf := newNodeFilter(opts...)
+	for v := range text {
+		_ = nf.AddRules(v)
+	}
+	return nf
+}
+
+// NewNodeFilter 创建规则过滤器，实现敏感词的过滤
+// 从切片中读取规则数据，无效的规则将被忽略，可先使用Compile检查规则
+func NewNodeFilter(text []string, opts ...filter.Option) filter.SensitivewordFilter {
+	nf := newNodeFilter(opts...)
+	for i, l := 0, len(text); i < l; i++ {
+		_ = nf.AddRules(text[i])
+	}
+	return nf
+}
+
+func newNode() *node {
+	return &node{
+		child: make(map[rune]*node),
+		edges: make(map[string]*edge),
+	}
+}
+
+// node 规则自动机上的一个状态
+// 字面字符经 child 转移，与字面敏感词共享前缀；字符类及重复片段经 edges 转移
+type node struct {
+	end   bool
+	rule  string
+	child map[rune]*node
+	edges map[string]*edge
+	order []string
+}
+
+// edge 匹配一个非字面片段后的转移
+type edge struct {
+	piece piece
+	next  *node
+}
+
+// NodeFilter 规则过滤器
+type NodeFilter struct {
+	root    *node
+	opts    []filter.Option
+	options *filter.Options
+	// mux 保护查找与增删规则的并发访问
+	mux sync.RWMutex
+}
+
+func newNodeFilter(opts ...filter.Option) *NodeFilter {
+	options := filter.NewOptions(opts...)
+	// 不支持派生写法及间隔，忽略相应的可选项
+	options.Expanders = nil
+	options.MaxGap = 0
+	options.GapClass = nil
+	return &NodeFilter{
+		root:    newNode(),
+		opts:    opts,
+		options: options,
+	}
+}
+
+// Options 获取创建过滤器时使用的可选项
+func (nf *NodeFilter) Options() []filter.Option {
+	return nf.opts
+}
+
+// AddRules 编译并添加规则，遇到无效的规则时返回error，之前的规则已被添加
+func (nf *NodeFilter) AddRules(rules ...string) error {
+	nf.mux.Lock()
+	defer nf.mux.Unlock()
+	for _, rule := range rules {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		p, err := Compile(rule)
+		if err != nil {
+			return err
+		}
+		n := nf.root
+		for _, pc := range p.pieces {
+			n = nf.step(n, pc, true)
+		}
+		n.end = true
+		n.rule = rule
+	}
+	return nil
+}
+
+// step 沿片段 pc 转移，create 为true时创建不存在的状态，否则返回nil
+func (nf *NodeFilter) step(n *node, pc piece, create bool) *node {
+	if r, ok := pc.literal(); ok {
+		r = nf.normalize(r)
+		next, ok := n.child[r]
+		if !ok && create {
+			next = newNode()
+			n.child[r] = next
+		}
+		return next
+	}
+	e, ok := n.edges[pc.key]
+	if !ok {
+		if !create {
+			return nil
+		}
+		pc.class = nf.normalizeClass(pc.class)
+		e = &edge{piece: pc, next: newNode()}
+		n.edges[pc.key] = e
+		n.order = append(n.order, pc.key)
+	}
+	return e.next
+}
+
+// normalize 对规则中的字面字符进行归一化，归一化为多个字符时保持原样
+func (nf *NodeFilter) normalize(r rune) rune {
+	if nf.options.Normalizer == nil {
+		return r
+	}
+	runes := nf.options.Normalizer.Normalize(nil, r)
+	if len(runes) != 1 {
+		return r
+	}
+	return runes[0]
+}
+
+// normalizeClass 对字符类中的字符及范围的上下界进行归一化，使其与归一化后的文本匹配
+// 归一化后的范围与原范围一同保留，上下界归一化后无效(如不再有序)时只保留原范围
+func (nf *NodeFilter) normalizeClass(c charClass) charClass {
+	if nf.options.Normalizer == nil {
+		return c
+	}
+	if c.lit != 0 {
+		c.lit = nf.normalize(c.lit)
+	}
+	ranges := make([]runeRange, 0, len(c.ranges)*2)
+	for _, rg := range c.ranges {
+		ranges = append(ranges, rg)
+		if lo, hi := nf.normalize(rg.lo), nf.normalize(rg.hi); (lo != rg.lo || hi != rg.hi) && lo <= hi {
+			ranges = append(ranges, runeRange{lo: lo, hi: hi})
+		}
+	}
+	c.ranges = ranges
+	return c
+}
+
+// Add 增加规则，无效的规则将被忽略
+func (nf *NodeFilter) Add(text ...string) {
+	for _, v := range text {
+		_ = nf.AddRules(v)
+	}
+}
+
+// Remove 移除规则
+func (nf *NodeFilter) Remove(text ...string) {
+	nf.mux.Lock()
+	defer nf.mux.Unlock()
+	for _, rule := range text {
+		rule = strings.TrimSpace(rule)
+		p, err := Compile(rule)
+		if err != nil {
+			continue
+		}
+		path := []*node{nf.root}
+		for _, pc := range p.pieces {
+			n := nf.step(path[len(path)-1], pc, false)
+			if n == nil {
+				break
+			}
+			path = append(path, n)
+		}
+		n := path[len(path)-1]
+		if len(path) != len(p.pieces)+1 || n.rule != rule {
+			continue
+		}
+		n.end = false
+		n.rule = ""
+		// 自末尾向前移除不再通向任何规则的状态
+		for i := len(p.pieces) - 1; i >= 0 && !path[i+1].end && path[i+1].empty(); i-- {
+			nf.unlink(path[i], p.pieces[i])
+		}
+	}
+}
+
+// empty 状态是否没有任何转移
+func (n *node) empty() bool {
+	return len(n.child) == 0 && len(n.edges) == 0
+}
+
+// unlink 移除状态 n 沿片段 pc 的转移
+func (nf *NodeFilter) unlink(n *node, pc piece) {
+	if r, ok := pc.literal(); ok {
+		delete(n.child, nf.normalize(r))
+		return
+	}
+	delete(n.edges, pc.key)
+	for i, key := range n.order {
+		if key == pc.key {
+			n.order = append(n.order[:i], n.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// scan 从每个位置开始查找命中规则的文本，以其字符区间 [start, end) 及规则的终止状态回调 fn，
+// 整词匹配时忽略不满足单词边界的区间，fn 返回false时停止查找
+func (nf *NodeFilter) scan(runes []rune, fn func(start, end int, n *node) bool) {
+	type state struct {
+		n   *node
+		pos int
+	}
+	nf.mux.RLock()
+	defer nf.mux.RUnlock()
+	visited := make(map[state]bool)
+	var walk func(start, pos int, n *node) bool
+	walk = func(start, pos int, n *node) bool {
+		s := state{n: n, pos: pos}
+		if visited[s] {
+			return true
+		}
+		visited[s] = true
+		if n.end && pos > start && (!nf.options.WholeWord || filter.IsWholeWord(runes, start, pos)) && !fn(start, pos, n) {
+			return false
+		}
+		if pos < len(runes) {
+			if next, ok := n.child[runes[pos]]; ok && !walk(start, pos+1, next) {
+				return false
+			}
+		}
+		for _, key := range n.order {
+			e := n.edges[key]
+			for k := 0; k <= e.piece.max; k++ {
+				if k >= e.piece.min && !walk(start, pos+k, e.next) {
+					return false
+				}
+				if pos+k >= len(runes) || !e.piece.class.match(runes[pos+k]) {
+					break
+				}
+			}
+		}
+		return true
+	}
+	for start := range runes {
+		for k := range visited {
+			delete(visited, k)
+		}
+		if !walk(start, start, nf.root) {
+			return
+		}
+	}
+}
+
+// FindMatches 查找文本中命中规则的所有文本及其在原文中的位置
+func (nf *NodeFilter) FindMatches(text string, opts ...filter.MatchOption) ([]filter.Match, error) {
+	o := filter.NewMatchOptions(opts...)
+	t := filter.NewText(text, o, nil, nf.options.Normalizer)
+	var matches []filter.Match
+	nf.scan(t.Runes, func(start, end int, n *node) bool {
+		m := t.Match(start, end)
+		m.Entry = n.rule
+		m.Rule = n.rule
+		matches = append(matches, m)
+		return true
+	})
+	filter.SortMatches(matches)
+	return filter.ResolveOverlaps(matches, o.Overlap), nil
+}
+
+func (nf *NodeFilter) Filter(text string, excludes ...rune) ([]string, error) {
+	data, err := nf.FilterResult(text, excludes...)
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	for k := range data {
+		result = append(result, k)
+	}
+	return result, nil
+}
+
+// FilterResult 返回命中规则的文本及出现次数，在空白及标点处切分文本
+func (nf *NodeFilter) FilterResult(text string, excludes ...rune) (map[string]int, error) {
+	o := filter.NewMatchOptions(filter.WithExcludes(excludes...))
+	t := filter.NewText(text, o, nil, nf.options.Normalizer)
+	data := make(map[string]int)
+	nf.segments(t.Runes, func(from, to int) bool {
+		nf.scan(t.Runes[from:to], func(start, end int, _ *node) bool {
+			if nf.options.SurfaceText {
+				data[t.Match(from+start, from+end).Surface(o)]++
+			} else {
+				data[string(t.Runes[from+start:from+end])]++
+			}
+			return true
+		})
+		return true
+	})
+	return data, nil
+}
+
+func (nf *NodeFilter) FilterReader(reader io.Reader, excludes ...rune) ([]string, error) {
+	buf := new(bytes.Buffer)
+	if _, err := io.Copy(buf, reader); err != nil {
+		return nil, err
+	}
+	return nf.Filter(buf.String(), excludes...)
+}
+
+func (nf *NodeFilter) FilterReaderResult(reader io.Reader, excludes ...rune) (map[string]int, error) {
+	buf := new(bytes.Buffer)
+	if _, err := io.Copy(buf, reader); err != nil {
+		return nil, err
+	}
+	return nf.FilterResult(buf.String(), excludes...)
+}
+
+func (nf *NodeFilter) Replace(text string, delim rune, excludes ...rune) (string, error) {
+	matches, err := nf.FindMatches(text, filter.WithExcludes(excludes...))
+	if err != nil {
+		return "", err
+	}
+	uchars := []rune(text)
+	for _, m := range matches {
+		m.Mask(uchars, delim)
+	}
+	return string(uchars), nil
+}
+
+// IsExist 是否存在命中规则的文本，在空白及标点处切分文本
+func (nf *NodeFilter) IsExist(text string, excludes ...rune) bool {
+	t := filter.NewText(text, filter.NewMatchOptions(filter.WithExcludes(excludes...)), nil, nf.options.Normalizer)
+	var exist bool
+	nf.segments(t.Runes, func(from, to int) bool {
+		nf.scan(t.Runes[from:to], func(_, _ int, _ *node) bool {
+			exist = true
+			return false
+		})
+		return !exist
+	})
+	return exist
+}
+
+// IsSeparator 实现filter.Segmenter接口，是否为切分文本的空白或标点，归一化为字母或数字的标点不切分文本
+func (nf *NodeFilter) IsSeparator(r rune) bool {
+	return filter.IsSeparator(nf.options.Normalizer, r)
+}
+
+// segments 在空白及标点处切分文本，依次以每一段的区间 [from, to) 回调，fn 返回false时停止
+func (nf *NodeFilter) segments(runes []rune, fn func(from, to int) bool) {
+	start := 0
+	for i := 0; i <= len(runes); i++ {
+		if i < len(runes) && !nf.IsSeparator(runes[i]) {
+			continue
+		}
+		if i > start && !fn(start, i) {
+			return
+		}
+		start = i + 1
+	}
+}
+
+func (nf *NodeFilter) IsExistReader(reader io.Reader, excludes ...rune) bool {
+	buf := new(bytes.Buffer)
+	if _, err := io.Copy(buf, reader); err != nil {
+		return false
+	}
+	return nf.IsExist(buf.String(), excludes...)
+}
@@ -0,0 +1,133 @@
+package rule
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hellobchain/sensitivewordfilter/filter"
+	"github.com/hellobchain/sensitivewordfilter/filter/filtertest"
+)
+
+func TestCompile(t *testing.T) {
+	valid := []string{"赌*场", "[微威]信", "fu?k", `\d{11}`, "[a-z]{2,4}", `\*\?`, "[^0-9]"}
+	for _, r := range valid {
+		if _, err := Compile(r); err != nil {
+			t.Errorf("compile %q: %v", r, err)
+		}
+	}
+	invalid := []string{"", "*", "a{0}", "[abc", "[]", "a{2", "a{3,1}", "a{100}", `a\`, "a}", "*{2}", "[z-a]"}
+	for _, r := range invalid {
+		if _, err := Compile(r); err == nil {
+			t.Errorf("compile %q expect error", r)
+		}
+	}
+}
+
+func TestFindMatches(t *testing.T) {
+	f := NewNodeFilter([]string{"赌*场", "[微威]信", "fu?k", `\d{11}`, "微博"})
+	matches, err := f.(filter.Matcher).FindMatches("加威信，去赌博的场子，fuck，电话13800138000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []filter.Match{
+		{Word: "威信", Entry: "[微威]信", Rule: "[微威]信", Start: 1, End: 3, ByteStart: 3, ByteEnd: 9},
+		{Word: "赌博的场", Entry: "赌*场", Rule: "赌*场", Start: 5, End: 9, ByteStart: 15, ByteEnd: 27},
+		{Word: "fuck", Entry: "fu?k", Rule: "fu?k", Start: 11, End: 15, ByteStart: 33, ByteEnd: 37},
+		{Word: "13800138000", Entry: `\d{11}`, Rule: `\d{11}`, Start: 18, End: 29, ByteStart: 46, ByteEnd: 57},
+	}
+	if !reflect.DeepEqual(matches, expect) {
+		t.Errorf("find matches got %+v, expect %+v", matches, expect)
+	}
+
+	text, err := f.Replace("加微信，去赌场", '*')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "加**，去**" {
+		t.Errorf("replace got %s", text)
+	}
+
+	if f.IsExist("赌123456789场") {
+		t.Errorf("expect wildcard limited to %d runes", MaxWildcard)
+	}
+	if !f.IsExist("上微博") {
+		t.Errorf("expect literal rule sharing prefix with class rule")
+	}
+
+	f.Remove("[微威]信")
+	if f.IsExist("微信") {
+		t.Errorf("expect rule removed")
+	}
+	if !f.IsExist("微博") {
+		t.Errorf("expect other rules kept")
+	}
+}
+
+func TestNormalizer(t *testing.T) {
+	f := NewNodeFilter([]string{"fu?k"}, filter.WithNormalizer(filter.DefaultNormalizer()))
+	if !f.IsExist("ＦＵＣＫ") {
+		t.Errorf("expect normalized text matched")
+	}
+}
+
+func TestOptions(t *testing.T) {
+	f := NewNodeFilter([]string{"ass", "a[0-9]{0,1}s"}, filter.WithWholeWord())
+	if f.IsExist("class") || f.IsExist("a1sk") {
+		t.Errorf("expect rules matched as whole words only")
+	}
+	if !f.IsExist("kick ass") || !f.IsExist("a1s") {
+		t.Errorf("expect whole word matched")
+	}
+
+	// 不支持的间隔可选项被忽略，间隔需在规则中表示
+	f = NewNodeFilter([]string{"ab"}, filter.WithMaxGap(1, nil))
+	if f.IsExist("axb") || !f.IsExist("ab") {
+		t.Errorf("expect max gap ignored")
+	}
+}
+
+func TestNormalizeClass(t *testing.T) {
+	f := NewNodeFilter([]string{`[A-Z]{2}\d`, "X{2}"}, filter.WithNormalizer(filter.CaseFold))
+	for _, text := range []string{"AB1", "ab1", "xx", "XX"} {
+		if !f.IsExist(text) {
+			t.Errorf("expect %q matched with case folded class", text)
+		}
+	}
+	if f.IsExist("a11") {
+		t.Errorf("expect digits not matched by letter range")
+	}
+}
+
+func TestRemovePrune(t *testing.T) {
+	f := newNodeFilter()
+	f.Add("微博")
+	for i := 0; i < 3; i++ {
+		f.Add("[微威]信", "赌*场", "微博客")
+		f.Remove("[微威]信", "赌*场", "微博客")
+	}
+	if !f.IsExist("微博") || f.IsExist("微信") || f.IsExist("赌博场") {
+		t.Errorf("unexpected matches after remove")
+	}
+	// 只保留 "微博" 的两个状态
+	count := 0
+	var walk func(n *node)
+	walk = func(n *node) {
+		count++
+		for _, next := range n.child {
+			walk(next)
+		}
+		for _, e := range n.edges {
+			walk(e.next)
+		}
+	}
+	walk(f.root)
+	if count != 3 || len(f.root.order) != 0 {
+		t.Errorf("expect dead states pruned, got %d states", count)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	filtertest.Run(t, func(words []string) filter.SensitivewordFilter {
+		return NewNodeFilter(words)
+	})
+}
@@ -0,0 +1,44 @@
+package filter
+
+import "unsafe"
+
+// Stats 过滤器内部结构的统计信息
+type Stats struct {
+	// Nodes 节点个数，包括根节点
+	Nodes int
+	// Words 词尾节点个数，包括派生写法
+	Words int
+	// Bytes 节点占用内存的估算值(字节)
+	Bytes int64
+	// Depth 最长的词尾路径的字符个数
+	Depth int
+}
+
+// Compactor 支持压缩内部结构的过滤器
+type Compactor interface {
+	// Compact 重建内部结构，删除不再通向词尾的节点并释放删除敏感词后残留的空间
+	// 压缩期间查找及增删敏感词将被阻塞
+	Compact()
+
+	// Stats 获取内部结构的统计信息
+	Stats() Stats
+}
+
+// MapBytes 估算含 n 个元素、每个元素的键值共占 size 字节的map占用的内存
+// map删除元素后不会缩容，实际占用可能大于估算值
+func MapBytes(n int, size uintptr) int64 {
+	const (
+		header      = 48
+		bucketCount = 8
+		loadFactor  = 6.5
+	)
+	if n == 0 {
+		return header
+	}
+	buckets := 1
+	for float64(n) > loadFactor*float64(buckets) {
+		buckets <<= 1
+	}
+	bucket := bucketCount + bucketCount*size + unsafe.Sizeof(uintptr(0))
+	return header + int64(buckets)*int64(bucket)
+}
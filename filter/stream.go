@@ -0,0 +1,176 @@
+package filter
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrStreamUnsupported 被包装的过滤器不支持流式匹配
+var ErrStreamUnsupported = errors.New("filter: stream matching not supported")
+
+// streamCheckInterval 流式匹配每读取多少个字符检查一次上下文是否结束
+const streamCheckInterval = 1024
+
+// Streamer 支持流式匹配的过滤器
+type Streamer interface {
+	// FilterStream 从可读流中查找敏感词，每找到一个即回调 fn，fn 返回false时停止查找
+	// 匹配状态跨越多次读取保持，不按空白及标点切分文本，占用的内存与最长敏感词的长度成正比
+	// 命中按结束位置的顺序回调，ctx 结束或读取出错时返回error
+	FilterStream(ctx context.Context, reader io.Reader, fn func(Match) bool) error
+}
+
+// Stream 流式匹配的输入，逐个返回排除指定字符并经过归一化后参与匹配的字符，
+// 并保留尚未释放的原文用于生成命中结果
+type Stream struct {
+	ctx  context.Context
+	rd   *bufio.Reader
+	opts *MatchOptions
+	n    Normalizer
+
+	// pending 当前原文字符归一化后尚未返回的字符
+	pending []rune
+	next    int
+	current int
+
+	// count, offset 已读取的原文字符个数及字节数
+	count  int
+	offset int
+	// window, offsets 从字符下标 base 开始尚未释放的原文字符及其字节偏移
+	window  []rune
+	offsets []int
+	base    int
+
+	// hist, returned 最近返回的参与匹配的字符及已返回的个数，用于判断单词边界
+	hist     []rune
+	returned int
+	// queue 等待回调的命中
+	queue []queuedMatch
+}
+
+// queuedMatch 等待回调的命中，end 为尚需判断是否为单词边界的词尾位置(已返回的字符个数)，0表示已确定
+type queuedMatch struct {
+	m   Match
+	end int
+}
+
+// NewStream 创建流式匹配的输入，o 中的去噪选项不适用于流式匹配
+func NewStream(ctx context.Context, reader io.Reader, o *MatchOptions, n Normalizer) *Stream {
+	return &Stream{
+		ctx:  ctx,
+		rd:   bufio.NewReader(reader),
+		opts: o,
+		n:    n,
+	}
+}
+
+// Next 返回下一个参与匹配的字符及其在原文中的字符下标，读取完毕时返回io.EOF
+func (s *Stream) Next() (rune, int, error) {
+	for s.next >= len(s.pending) {
+		if s.count%streamCheckInterval == 0 {
+			if err := s.ctx.Err(); err != nil {
+				return 0, 0, err
+			}
+		}
+		r, size, err := s.rd.ReadRune()
+		if err != nil {
+			return 0, 0, err
+		}
+		s.current = s.count
+		s.window = append(s.window, r)
+		s.offsets = append(s.offsets, s.offset)
+		s.count++
+		s.offset += size
+		s.next = 0
+		s.pending = s.pending[:0]
+		if !s.opts.IsExclude(r) {
+			s.pending = NormalizeRune(s.n, s.pending, r)
+		}
+	}
+	r := s.pending[s.next]
+	s.next++
+	s.returned++
+	if len(s.hist) == 2*BoundaryContext {
+		s.hist = s.hist[:copy(s.hist, s.hist[1:])]
+	}
+	s.hist = append(s.hist, r)
+	return r, s.current, nil
+}
+
+// WordStart 上一次 Next 返回的字符是否满足整词匹配的开头
+func (s *Stream) WordStart() bool {
+	return IsWordStart(s.hist, len(s.hist)-1)
+}
+
+// Push 加入待回调的命中，命中需以上一次 Next 返回的字符结尾
+// wholeWord 为true时词尾需为单词边界，需再读取 BoundaryContext 个字符才能确定
+func (s *Stream) Push(m Match, wholeWord bool) {
+	q := queuedMatch{m: m}
+	if wholeWord {
+		q.end = s.returned
+	}
+	s.queue = append(s.queue, q)
+}
+
+// Flush 按加入的顺序回调已确定的命中，final 为true时以文本结尾判断尚未确定的词尾
+// fn 返回false时停止回调并返回false
+func (s *Stream) Flush(final bool, fn func(Match) bool) bool {
+	for len(s.queue) > 0 {
+		q := s.queue[0]
+		if q.end > 0 {
+			if !final && s.returned < q.end+BoundaryContext {
+				return true
+			}
+			base := s.returned - len(s.hist)
+			from := q.end - BoundaryContext
+			if from < base {
+				from = base
+			}
+			if !IsWordEnd(s.hist[from-base:], q.end-from) {
+				s.queue = s.queue[1:]
+				continue
+			}
+		}
+		s.queue = s.queue[1:]
+		if !fn(q.m) {
+			return false
+		}
+	}
+	return true
+}
+
+// Match 生成原文字符区间 [start, end) 的命中，start 不能早于已释放的位置
+// gaps 为区间内作为间隔的原文字符下标，命中的词条信息由调用方填充
+func (s *Stream) Match(start, end int, gaps []int) Match {
+	m := Match{
+		Start:     start,
+		End:       end,
+		ByteStart: s.offsets[start-s.base],
+		ByteEnd:   s.offset,
+		Word:      string(s.window[start-s.base : end-s.base]),
+	}
+	if end-s.base < len(s.offsets) {
+		m.ByteEnd = s.offsets[end-s.base]
+	}
+	for _, i := range gaps {
+		if len(m.Gaps) == 0 || m.Gaps[len(m.Gaps)-1] != i {
+			m.Gaps = append(m.Gaps, i)
+		}
+	}
+	return m
+}
+
+// Release 释放原文中字符下标 start 之前的字符
+func (s *Stream) Release(start int) {
+	if start <= s.base {
+		return
+	}
+	n := start - s.base
+	if n > len(s.window) {
+		n = len(s.window)
+	}
+	s.window = s.window[n:]
+	s.offsets = s.offsets[n:]
+	s.base += n
+}
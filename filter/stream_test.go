@@ -0,0 +1,125 @@
+package filter_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"unicode"
+
+	"github.com/hellobchain/sensitivewordfilter/filter"
+	"github.com/hellobchain/sensitivewordfilter/filter/ac"
+	"github.com/hellobchain/sensitivewordfilter/filter/dfa"
+	"github.com/hellobchain/sensitivewordfilter/filter/newdfa"
+)
+
+func streamers() map[string]filter.Streamer {
+	words := []string{"暴力", "广告", "hello world"}
+	return map[string]filter.Streamer{
+		"dfa":         dfa.NewNodeFilter(words).(filter.Streamer),
+		"newdfa":      newdfa.NewNodeFilter(words).(filter.Streamer),
+		"doublearray": newdfa.NewNodeFilter(words, filter.WithDoubleArray()).(filter.Streamer),
+		"ac":          ac.NewNodeFilter(words).(filter.Streamer),
+	}
+}
+
+func TestFilterStream(t *testing.T) {
+	const text = "这是暴力，say hello world广告"
+	type hit struct {
+		Entry                          string
+		Start, End, ByteStart, ByteEnd int
+	}
+	expect := []hit{
+		{"暴力", 2, 4, 6, 12},
+		{"hello world", 9, 20, 19, 30},
+		{"广告", 20, 22, 30, 36},
+	}
+	for name, s := range streamers() {
+		var got []hit
+		// 每次只读取一个字节，敏感词必然跨越多次读取
+		err := s.FilterStream(context.Background(), iotest.OneByteReader(strings.NewReader(text)), func(m filter.Match) bool {
+			if text[m.ByteStart:m.ByteEnd] != m.Word {
+				t.Errorf("%s: byte offsets %d-%d do not cover %q", name, m.ByteStart, m.ByteEnd, m.Word)
+			}
+			got = append(got, hit{m.Entry, m.Start, m.End, m.ByteStart, m.ByteEnd})
+			return true
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(got, expect) {
+			t.Errorf("%s: got %+v, expect %+v", name, got, expect)
+		}
+	}
+}
+
+func TestFilterStreamStop(t *testing.T) {
+	text := strings.Repeat("暴力", 100)
+	for name, s := range streamers() {
+		count := 0
+		err := s.FilterStream(context.Background(), strings.NewReader(text), func(m filter.Match) bool {
+			count++
+			return count < 3
+		})
+		if err != nil || count != 3 {
+			t.Errorf("%s: expect stop after 3 matches, got %d %v", name, count, err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err = s.FilterStream(ctx, strings.NewReader(text), func(m filter.Match) bool {
+			t.Errorf("%s: unexpected match after cancel", name)
+			return true
+		})
+		if err != context.Canceled {
+			t.Errorf("%s: expect context.Canceled, got %v", name, err)
+		}
+	}
+}
+
+func TestMetaFilterStream(t *testing.T) {
+	mf := filter.NewMetaFilter(newdfa.NewNodeFilter(nil).(filter.MatchFilter), nil)
+	mf.AddWithMeta("暴力", filter.Meta{Category: "abuse"})
+	var got []filter.Meta
+	err := mf.FilterStream(context.Background(), strings.NewReader("暴力"), func(m filter.Match) bool {
+		got = append(got, m.Meta)
+		return true
+	})
+	if err != nil || !reflect.DeepEqual(got, []filter.Meta{{Category: "abuse"}}) {
+		t.Errorf("got %+v %v", got, err)
+	}
+}
+
+func TestFilterStreamGap(t *testing.T) {
+	const text = "暴   力，暴 力"
+	for name, s := range streamers() {
+		err := s.FilterStream(context.Background(), strings.NewReader(text), func(m filter.Match) bool {
+			t.Errorf("%s: unexpected match %+v without gap matching", name, m)
+			return true
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+
+	// 允许间隔时空白作为间隔字符记录在 Gaps 中，与非流式匹配相同
+	opts := []filter.Option{filter.WithMaxGap(3, unicode.IsSpace)}
+	for name, f := range map[string]filter.SensitivewordFilter{
+		"dfa":    dfa.NewNodeFilter([]string{"暴力"}, opts...),
+		"newdfa": newdfa.NewNodeFilter([]string{"暴力"}, opts...),
+	} {
+		expect, _ := f.(filter.Matcher).FindMatches(text)
+		var got []filter.Match
+		err := f.(filter.Streamer).FilterStream(context.Background(), strings.NewReader(text), func(m filter.Match) bool {
+			got = append(got, m)
+			return true
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(got) != 2 || !reflect.DeepEqual(got, expect) {
+			t.Errorf("%s: got %+v, expect %+v", name, got, expect)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package filter
+
+import (
+	"bytes"
+	"io"
+)
+
+// MatchFilter 同时提供敏感词过滤及带位置信息查找的过滤器
+type MatchFilter interface {
+	SensitivewordFilter
+	Matcher
+}
+
+// NewWhitelistFilter 使用白名单包装敏感词过滤器
+// whitelist 用于查找白名单短语，完全落在同一位置白名单短语内的敏感词将被忽略
+func NewWhitelistFilter(f MatchFilter, whitelist Matcher) *WhitelistFilter {
+	return &WhitelistFilter{
+		filter:    f,
+		whitelist: whitelist,
+	}
+}
+
+// WhitelistFilter 支持白名单的敏感词过滤器
+type WhitelistFilter struct {
+	filter    MatchFilter
+	whitelist Matcher
+}
+
+// Unwrap 获取被包装的敏感词过滤器
+func (wf *WhitelistFilter) Unwrap() MatchFilter {
+	return wf.filter
+}
+
+func (wf *WhitelistFilter) Add(text ...string) {
+	wf.filter.Add(text...)
+}
+
+func (wf *WhitelistFilter) Remove(text ...string) {
+	wf.filter.Remove(text...)
+}
+
+// FindMatches 查找文本中出现的所有敏感词，忽略白名单短语内的命中后再处理区间重叠的命中
+func (wf *WhitelistFilter) FindMatches(text string, opts ...MatchOption) ([]Match, error) {
+	matches, _, err := wf.split(text, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return ResolveOverlaps(matches, NewMatchOptions(opts...).Overlap), nil
+}
+
+// split 查找文本中出现的所有敏感词，分为白名单短语以外及以内的命中
+func (wf *WhitelistFilter) split(text string, opts ...MatchOption) ([]Match, []Match, error) {
+	opts = append(opts[:len(opts):len(opts)], WithOverlap(OverlapAll))
+	matches, err := wf.filter.FindMatches(text, opts...)
+	if err != nil || len(matches) == 0 {
+		return matches, nil, err
+	}
+	allows, err := wf.whitelist.FindMatches(text, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(allows) == 0 {
+		return matches, nil, nil
+	}
+	var kept, allowed []Match
+	for _, m := range matches {
+		if isAllowed(m, allows) {
+			allowed = append(allowed, m)
+		} else {
+			kept = append(kept, m)
+		}
+	}
+	return kept, allowed, nil
+}
+
+func isAllowed(m Match, allows []Match) bool {
+	for _, a := range allows {
+		if a.Start <= m.Start && m.End <= a.End {
+			return true
+		}
+	}
+	return false
+}
+
+// optionsOf 获取过滤器(或其包装的过滤器)创建时使用的配置，无法获取时返回默认配置
+func optionsOf(f interface{}) *Options {
+	for {
+		switch ft := f.(type) {
+		case interface{ Options() []Option }:
+			return NewOptions(ft.Options()...)
+		case interface{ Unwrap() MatchFilter }:
+			f = ft.Unwrap()
+		default:
+			return NewOptions()
+		}
+	}
+}
+
+// resultKey 返回命中在 FilterResult 结果中的文本，即除排除字符及间隔字符以外的文本，未设置 SurfaceText 时为归一化后的文本
+func resultKey(m Match, o *MatchOptions, options *Options) string {
+	if options.SurfaceText {
+		return m.Surface(o)
+	}
+	return NormalizeString(options.Normalizer, m.Surface(o))
+}
+
+// Segmenter 在分隔字符处切分文本的过滤器，Filter、FilterResult 及 IsExist 等方法的命中不会跨越分隔字符
+type Segmenter interface {
+	// IsSeparator 字符 r 是否为切分文本的分隔字符
+	IsSeparator(r rune) bool
+}
+
+// separatorOf 获取过滤器(或其包装的过滤器)切分文本的规则
+// 未实现Segmenter接口时，未开启间隔匹配的过滤器在空白及标点处切分文本
+func separatorOf(f interface{}) func(r rune) bool {
+	for {
+		switch ft := f.(type) {
+		case Segmenter:
+			return ft.IsSeparator
+		case interface{ Unwrap() MatchFilter }:
+			f = ft.Unwrap()
+		default:
+			options := optionsOf(f)
+			return func(r rune) bool {
+				return options.MaxGap == 0 && IsSeparator(options.Normalizer, r)
+			}
+		}
+	}
+}
+
+// separated 命中是否跨越分隔字符
+func separated(m Match, o *MatchOptions, isSeparator func(rune) bool) bool {
+	for _, r := range m.Word {
+		if !o.IsExclude(r) && isSeparator(r) {
+			return true
+		}
+	}
+	return false
+}
+
+func (wf *WhitelistFilter) Filter(text string, excludes ...rune) ([]string, error) {
+	data, err := wf.FilterResult(text, excludes...)
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	for k := range data {
+		result = append(result, k)
+	}
+	return result, nil
+}
+
+// FilterResult 与被包装的过滤器的结果相同(包括文本的切分及结果中的文本)，但不包括白名单短语内的命中
+func (wf *WhitelistFilter) FilterResult(text string, excludes ...rune) (map[string]int, error) {
+	data, err := wf.filter.FilterResult(text, excludes...)
+	if err != nil || len(data) == 0 {
+		return data, err
+	}
+	_, allowed, err := wf.split(text, WithExcludes(excludes...))
+	if err != nil {
+		return nil, err
+	}
+	var (
+		o           = NewMatchOptions(WithExcludes(excludes...))
+		options     = optionsOf(wf.filter)
+		isSeparator = separatorOf(wf.filter)
+	)
+	for _, m := range allowed {
+		// 被包装的过滤器切分文本时，跨越分隔字符的命中不在其结果中
+		if separated(m, o, isSeparator) {
+			continue
+		}
+		key := resultKey(m, o, options)
+		if data[key]--; data[key] <= 0 {
+			delete(data, key)
+		}
+	}
+	return data, nil
+}
+
+func (wf *WhitelistFilter) FilterReader(reader io.Reader, excludes ...rune) ([]string, error) {
+	buf := new(bytes.Buffer)
+	if _, err := io.Copy(buf, reader); err != nil {
+		return nil, err
+	}
+	return wf.Filter(buf.String(), excludes...)
+}
+
+func (wf *WhitelistFilter) FilterReaderResult(reader io.Reader, excludes ...rune) (map[string]int, error) {
+	buf := new(bytes.Buffer)
+	if _, err := io.Copy(buf, reader); err != nil {
+		return nil, err
+	}
+	return wf.FilterResult(buf.String(), excludes...)
+}
+
+func (wf *WhitelistFilter) Replace(text string, delim rune, excludes ...rune) (string, error) {
+	matches, err := wf.FindMatches(text, WithExcludes(excludes...))
+	if err != nil {
+		return "", err
+	}
+	uchars := []rune(text)
+	for _, m := range matches {
+		m.Mask(uchars, delim)
+	}
+	return string(uchars), nil
+}
+
+// ReplaceWith 实现Rewriter接口，白名单中的命中不会被替换
+func (wf *WhitelistFilter) ReplaceWith(text string, r Replacer, opts ...MatchOption) (string, error) {
+	matches, err := wf.FindMatches(text, opts...)
+	if err != nil {
+		return "", err
+	}
+	return ReplaceMatches(text, matches, r), nil
+}
+
+// IsExist 与被包装的过滤器的结果相同，但忽略白名单短语内的命中
+func (wf *WhitelistFilter) IsExist(text string, excludes ...rune) bool {
+	if !wf.filter.IsExist(text, excludes...) {
+		return false
+	}
+	data, err := wf.FilterResult(text, excludes...)
+	return err == nil && len(data) > 0
+}
+
+func (wf *WhitelistFilter) IsExistReader(reader io.Reader, excludes ...rune) bool {
+	buf := new(bytes.Buffer)
+	if _, err := io.Copy(buf, reader); err != nil {
+		return false
+	}
+	return wf.IsExist(buf.String(), excludes...)
+}
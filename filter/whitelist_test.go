@@ -0,0 +1,126 @@
+package filter_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hellobchain/sensitivewordfilter/filter"
+	"github.com/hellobchain/sensitivewordfilter/filter/dfa"
+	"github.com/hellobchain/sensitivewordfilter/filter/filtertest"
+	"github.com/hellobchain/sensitivewordfilter/filter/newdfa"
+)
+
+func TestWhitelistFilter(t *testing.T) {
+	wf := filter.NewWhitelistFilter(
+		newdfa.NewNodeFilter([]string{"力", "cunt"}).(filter.MatchFilter),
+		newdfa.NewNodeFilter([]string{"力量", "Scunthorpe"}).(filter.Matcher),
+	)
+
+	testcases := []struct {
+		Text          string
+		ExpectResult  map[string]int
+		ExpectReplace string
+	}{
+		{"力量很大", map[string]int{}, "力量很大"},
+		{"暴力和力量", map[string]int{"力": 1}, "暴*和力量"},
+		{"Scunthorpe United", map[string]int{}, "Scunthorpe United"},
+		{"cunt", map[string]int{"cunt": 1}, "****"},
+	}
+
+	for _, tc := range testcases {
+		got, err := wf.FilterResult(tc.Text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(tc.ExpectResult, got) {
+			t.Errorf("filter result %s, got %v, expect %v", tc.Text, got, tc.ExpectResult)
+		}
+		replaced, err := wf.Replace(tc.Text, '*')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if replaced != tc.ExpectReplace {
+			t.Errorf("replace %s, got %s, expect %s", tc.Text, replaced, tc.ExpectReplace)
+		}
+		if exist := wf.IsExist(tc.Text); exist != (len(tc.ExpectResult) > 0) {
+			t.Errorf("is exist %s, got %v", tc.Text, exist)
+		}
+	}
+}
+
+func TestWhitelistFilterSemantics(t *testing.T) {
+	for name, f := range map[string]filter.MatchFilter{
+		"dfa":    dfa.NewNodeFilter([]string{"hello", "hello world"}, filter.WithNormalizer(filter.CaseFold)).(filter.MatchFilter),
+		"newdfa": newdfa.NewNodeFilter([]string{"hello", "hello world"}, filter.WithNormalizer(filter.CaseFold)).(filter.MatchFilter),
+	} {
+		wf := filter.NewWhitelistFilter(f, newdfa.NewNodeFilter([]string{"hello kitty"}, filter.WithNormalizer(filter.CaseFold)).(filter.Matcher))
+		// 与被包装的过滤器相同地切分文本，结果中为归一化后的文本
+		for text, expect := range map[string]map[string]int{
+			"say hello world":     {"hello": 1},
+			"HELLO, HELLO kitty":  {"hello": 1},
+			"Hello Kitty":         {},
+			"hello kitty, hello!": {"hello": 1},
+		} {
+			got, err := wf.FilterResult(text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, expect) {
+				t.Errorf("%s filter result %q, got %v, expect %v", name, text, got, expect)
+			}
+			if exist := wf.IsExist(text); exist != (len(expect) > 0) {
+				t.Errorf("%s is exist %q, got %v", name, text, exist)
+			}
+		}
+	}
+}
+
+func TestWhitelistSegmenter(t *testing.T) {
+	// 整词匹配或间隔匹配时被包装的过滤器不在标点处切分文本，跨越标点的命中在白名单短语内时同样被忽略
+	testcases := map[string]struct {
+		f      filter.SensitivewordFilter
+		allow  string
+		expect map[string]map[string]int
+	}{
+		"wholeword": {
+			f:     newdfa.NewNodeFilter([]string{"kick-ass"}, filter.WithWholeWord()),
+			allow: "kick-ass movie",
+			expect: map[string]map[string]int{
+				"a kick-ass movie":         {},
+				"kick-ass, kick-ass movie": {"kick-ass": 1},
+			},
+		},
+		"gap": {
+			f:     newdfa.NewNodeFilter([]string{"ab"}, filter.WithMaxGap(1, nil)),
+			allow: "a-b c",
+			expect: map[string]map[string]int{
+				"a-b c":       {},
+				"a-b d a-b c": {"ab": 1},
+			},
+		},
+	}
+	for name, tc := range testcases {
+		wf := filter.NewWhitelistFilter(tc.f.(filter.MatchFilter), newdfa.NewNodeFilter([]string{tc.allow}).(filter.Matcher))
+		for text, expect := range tc.expect {
+			got, err := wf.FilterResult(text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, expect) {
+				t.Errorf("%s filter result %q, got %v, expect %v", name, text, got, expect)
+			}
+			if exist := wf.IsExist(text); exist != (len(expect) > 0) {
+				t.Errorf("%s is exist %q, got %v", name, text, exist)
+			}
+		}
+	}
+}
+
+func TestWhitelistConformance(t *testing.T) {
+	filtertest.Run(t, func(words []string) filter.SensitivewordFilter {
+		return filter.NewWhitelistFilter(
+			dfa.NewNodeFilter(words).(filter.MatchFilter),
+			newdfa.NewNodeFilter([]string{"力量"}).(filter.Matcher),
+		)
+	})
+}
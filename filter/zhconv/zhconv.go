@@ -0,0 +1,63 @@
+// Package zhconv 提供繁体中文到简体中文的字符转换，
+// 用于敏感词在繁简体之间的等价匹配
+package zhconv
+
+import (
+	_ "embed"
+	"strings"
+	"sync"
+
+	"github.com/hellobchain/sensitivewordfilter/filter"
+)
+
+//go:embed t2s.txt
+var t2sData string
+
+var (
+	t2sOnce sync.Once
+	t2s     map[rune]rune
+)
+
+func loadT2S() {
+	lines := strings.Split(t2sData, "\n")
+	t2s = make(map[rune]rune, len(lines))
+	for _, line := range lines {
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		pair := []rune(line)
+		if len(pair) != 3 || pair[1] != '\t' {
+			continue
+		}
+		t2s[pair[0]] = pair[2]
+	}
+}
+
+// ToSimplified 将繁体字转换为简体字，非繁体字原样返回
+func ToSimplified(r rune) rune {
+	t2sOnce.Do(loadT2S)
+	if s, ok := t2s[r]; ok {
+		return s
+	}
+	return r
+}
+
+// ToSimplifiedString 将字符串中的繁体字转换为简体字
+func ToSimplifiedString(text string) string {
+	return strings.Map(ToSimplified, text)
+}
+
+// Normalizer 繁体转简体的归一化，敏感词及待检测文本都统一为简体
+var Normalizer filter.Normalizer = filter.NormalizerFunc(func(dst []rune, r rune) []rune {
+	return append(dst, ToSimplified(r))
+})
+
+// WithVariants 开启繁简体等价匹配，简体或繁体的敏感词都能匹配另一种写法的文本，
+// Filter、FilterResult 等返回原文中的命中文本(见 filter.WithSurfaceText)
+// 繁转简在已设置的归一化处理之后进行(见 filter.AppendNormalizer)
+func WithVariants() filter.Option {
+	return func(o *filter.Options) {
+		filter.AppendNormalizer(Normalizer)(o)
+		filter.WithSurfaceText()(o)
+	}
+}
@@ -0,0 +1,66 @@
+package zhconv
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hellobchain/sensitivewordfilter/filter"
+	"github.com/hellobchain/sensitivewordfilter/filter/dfa"
+	"github.com/hellobchain/sensitivewordfilter/filter/newdfa"
+	"github.com/hellobchain/sensitivewordfilter/filter/rule"
+)
+
+func TestToSimplifiedString(t *testing.T) {
+	testcases := []struct {
+		Text   string
+		Expect string
+	}{
+		{"臺獨", "台独"},
+		{"暴力", "暴力"},
+		{"發財", "发财"},
+		{"hello", "hello"},
+	}
+
+	for _, tc := range testcases {
+		if got := ToSimplifiedString(tc.Text); got != tc.Expect {
+			t.Errorf("to simplified %s, got %s, expect %s", tc.Text, got, tc.Expect)
+		}
+	}
+}
+
+func TestWithVariants(t *testing.T) {
+	filters := map[string]filter.SensitivewordFilter{
+		"dfa":    dfa.NewNodeFilter([]string{"台独", "發財"}, WithVariants()),
+		"newdfa": newdfa.NewNodeFilter([]string{"台独", "發財"}, WithVariants()),
+		"rule":   rule.NewNodeFilter([]string{"台独", "發財"}, WithVariants()),
+	}
+
+	for name, f := range filters {
+		matches, err := f.(filter.Matcher).FindMatches("支持臺獨，恭喜发财")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Rule 只由规则过滤器设置，不参与比较
+		for i := range matches {
+			matches[i].Rule = ""
+		}
+		expect := []filter.Match{
+			{Word: "臺獨", Entry: "台独", Start: 2, End: 4, ByteStart: 6, ByteEnd: 12},
+			{Word: "发财", Entry: "發財", Start: 7, End: 9, ByteStart: 21, ByteEnd: 27},
+		}
+		if !reflect.DeepEqual(matches, expect) {
+			t.Errorf("%s find matches got %+v, expect %+v", name, matches, expect)
+		}
+		if got, _ := f.Replace("臺獨", '*'); got != "**" {
+			t.Errorf("%s replace got %s, expect **", name, got)
+		}
+		// 结果为原文中的命中文本，繁简体写法分别计数
+		result, err := f.FilterResult("他支持臺獨，也支持台独")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expect := map[string]int{"臺獨": 1, "台独": 1}; !reflect.DeepEqual(result, expect) {
+			t.Errorf("%s filter result got %v, expect %v", name, result, expect)
+		}
+	}
+}
@@ -6,5 +6,7 @@ require (
 	github.com/antlinker/go-cmap v0.0.0-20160407022646-0c5e57012e96
 	github.com/globalsign/mgo v0.0.0-20181015135952-eeefdecb41b8
 	github.com/go-redis/redis v6.15.9+incompatible
+	github.com/mozillazg/go-pinyin v0.20.0
 	github.com/syndtr/goleveldb v1.0.0
+	golang.org/x/text v0.3.7
 )
@@ -0,0 +1,78 @@
+package sensitivewordfilter
+
+import "time"
+
+// ReloadStats 重新加载的统计信息
+type ReloadStats struct {
+	// Words 加载的敏感词个数，未重新加载敏感词或增量更新时为0
+	Words int
+	// Excludes 加载的白名单短语个数，未重新加载白名单时为0
+	Excludes int
+	// Version 当前敏感词存储的版本号
+	Version uint64
+	// ExcludesVersion 当前白名单存储的版本号
+	ExcludesVersion uint64
+	// Incremental 是否通过应用变更增量更新
+	Incremental bool
+	// Added, Removed 增量更新时新增(或更新)及移除的词条个数
+	Added   int
+	Removed int
+	// Duration 重新加载耗时
+	Duration time.Duration
+}
+
+type reloadHooks struct {
+	start   []func()
+	success []func(ReloadStats)
+	err     []func(error)
+}
+
+// OnReloadStart 注册开始重新加载时调用的函数
+func (dm *SensitivewordManager) OnReloadStart(fn func()) {
+	dm.hookMux.Lock()
+	dm.hooks.start = append(dm.hooks.start, fn)
+	dm.hookMux.Unlock()
+}
+
+// OnReloadSuccess 注册重新加载成功时调用的函数
+func (dm *SensitivewordManager) OnReloadSuccess(fn func(stats ReloadStats)) {
+	dm.hookMux.Lock()
+	dm.hooks.success = append(dm.hooks.success, fn)
+	dm.hookMux.Unlock()
+}
+
+// OnReloadError 注册重新加载失败时调用的函数，失败时仍使用之前的过滤器
+func (dm *SensitivewordManager) OnReloadError(fn func(err error)) {
+	dm.hookMux.Lock()
+	dm.hooks.err = append(dm.hooks.err, fn)
+	dm.hookMux.Unlock()
+}
+
+func (dm *SensitivewordManager) reloadStart() {
+	dm.hookMux.RLock()
+	hooks := dm.hooks.start
+	dm.hookMux.RUnlock()
+	for _, fn := range hooks {
+		fn()
+	}
+}
+
+func (dm *SensitivewordManager) reloadSuccess(stats ReloadStats) {
+	dm.hookMux.RLock()
+	hooks := dm.hooks.success
+	dm.hookMux.RUnlock()
+	for _, fn := range hooks {
+		fn(stats)
+	}
+}
+
+// reloadError 调用重新加载失败的函数并返回err
+func (dm *SensitivewordManager) reloadError(err error) error {
+	dm.hookMux.RLock()
+	hooks := dm.hooks.err
+	dm.hookMux.RUnlock()
+	for _, fn := range hooks {
+		fn(err)
+	}
+	return err
+}
@@ -1,13 +1,19 @@
 package sensitivewordfilter
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/hellobchain/sensitivewordfilter/filter/newdfa"
 
 	"github.com/hellobchain/sensitivewordfilter/filter"
+	"github.com/hellobchain/sensitivewordfilter/filter/ac"
 	"github.com/hellobchain/sensitivewordfilter/filter/dfa"
+	"github.com/hellobchain/sensitivewordfilter/filter/rule"
 	"github.com/hellobchain/sensitivewordfilter/store"
 )
 
@@ -16,25 +22,58 @@ const (
 	DefaultCheckInterval = time.Second * 5
 )
 
+// ErrClosed 敏感词管理已关闭
+var ErrClosed = errors.New("sensitivewordfilter: manager closed")
+
+// ErrUnsupportedFilter 无法从存储重新创建当前的过滤器实现
+var ErrUnsupportedFilter = errors.New("sensitivewordfilter: unsupported filter")
+
+// ErrEmptyDictionary 从存储读取的敏感词为空，可使用 SetAllowEmptyDictionary 允许应用空的敏感词库
+var ErrEmptyDictionary = errors.New("sensitivewordfilter: empty dictionary")
+
 // NewSensitivewordManager 使用敏感词存储接口创建敏感词管理的实例
+// 提供 checkInterval 时自动重新加载：存储支持监听(store.Watchable)时在变更后立即重新加载，
+// 否则按该频率检查存储版本，使用 Close 停止
 func NewSensitivewordManager(sensitivewordStore store.SensitivewordStore, excludesStore store.SensitivewordStore, filter filter.SensitivewordFilter, checkInterval ...time.Duration) *SensitivewordManager {
+	return NewSensitivewordManagerContext(context.Background(), sensitivewordStore, excludesStore, filter, checkInterval...)
+}
+
+// NewSensitivewordManagerContext 使用敏感词存储接口创建敏感词管理的实例
+// ctx 取消或调用 Close 时停止检查存储版本
+func NewSensitivewordManagerContext(ctx context.Context, sensitivewordStore store.SensitivewordStore, excludesStore store.SensitivewordStore, filter filter.SensitivewordFilter, checkInterval ...time.Duration) *SensitivewordManager {
 	interval := DefaultCheckInterval
 	if len(checkInterval) == 0 {
 		interval = -1
 	} else {
 		interval = checkInterval[0]
 	}
+	ctx, cancel := context.WithCancel(ctx)
 	manage := &SensitivewordManager{
 		sensitivewordStore: sensitivewordStore,
 		excludesStore:      excludesStore,
 		version:            sensitivewordStore.Version(),
 		filter:             filter,
 		interval:           interval,
+		policy:             DefaultPolicy(),
+		reloading:          make(chan struct{}, 1),
+		cancel:             cancel,
 	}
-	if interval != -1 {
-		go func() {
-			manage.checkVersion()
-		}()
+	_, manage.metas, _ = readDict(sensitivewordStore)
+	manage.metaFilter = withMetas(filter, manage.metas)
+	if excludesStore != nil {
+		manage.excludesVersion = excludesStore.Version()
+		if allows, err := excludesStore.ReadAll(); err == nil {
+			manage.whitelist = manage.buildWhitelist(allows)
+		}
+	}
+	if interval > 0 {
+		words := watch(ctx, sensitivewordStore)
+		var excludes <-chan uint64
+		if excludesStore != nil {
+			excludes = watch(ctx, excludesStore)
+		}
+		manage.wg.Add(1)
+		go manage.checkVersion(ctx, words, excludes)
 	}
 	return manage
 }
@@ -44,27 +83,302 @@ type SensitivewordManager struct {
 	sensitivewordStore store.SensitivewordStore
 	excludesStore      store.SensitivewordStore
 	filter             filter.SensitivewordFilter
+	whitelist          filter.SensitivewordFilter
+	metaFilter         filter.SensitivewordFilter
+	metas              map[string]filter.Meta
+	policy             *Policy
+	policyLoader       func() (*Policy, error)
+	allowEmpty         bool
 	filterMux          sync.RWMutex
 	version            uint64
+	excludesVersion    uint64
 	interval           time.Duration
+	reloading          chan struct{}
+	cancel             context.CancelFunc
+	wg                 sync.WaitGroup
+	closed             int32
+	hookMux            sync.RWMutex
+	hooks              reloadHooks
+}
+
+// build 使用与当前过滤器相同的实现，使用 words 创建新的过滤器
+// 无法识别的过滤器实现返回nil
+func (dm *SensitivewordManager) build(words []string) filter.SensitivewordFilter {
+	switch ft := dm.filter.(type) {
+	case *dfa.NodeFilter:
+		return dfa.NewNodeFilter(words, ft.Options()...)
+	case *newdfa.NodeFilter:
+		return newdfa.NewNodeFilter(words, ft.Options()...)
+	case *ac.NodeFilter:
+		return ac.NewNodeFilter(words)
+	case *rule.NodeFilter:
+		return rule.NewNodeFilter(words, ft.Options()...)
+	}
+	return nil
+}
+
+// buildWhitelist 使用 allows 创建查找白名单短语的过滤器
+// 白名单短语按字面匹配(不会作为规则编译)，只使用与当前过滤器相同的归一化，
+// 不使用派生写法(如拼音)及间隔匹配，以免放行运营人员未列出的写法
+func (dm *SensitivewordManager) buildWhitelist(allows []string) filter.SensitivewordFilter {
+	var opts []filter.Option
+	if o, ok := dm.filter.(interface{ Options() []filter.Option }); ok {
+		if n := filter.NewOptions(o.Options()...).Normalizer; n != nil {
+			opts = append(opts, filter.WithNormalizer(n))
+		}
+	}
+	return newdfa.NewNodeFilter(allows, opts...)
+}
+
+// readDict 从存储读取所有敏感词，支持附加信息的存储同时返回词条的附加信息
+func readDict(s store.SensitivewordStore) ([]string, map[string]filter.Meta, error) {
+	es, ok := s.(store.EntryStore)
+	if !ok {
+		words, err := s.ReadAll()
+		return words, nil, err
+	}
+	entries, err := es.ReadAllEntries()
+	if err != nil {
+		return nil, nil, err
+	}
+	words := make([]string, len(entries))
+	metas := make(map[string]filter.Meta)
+	for i, entry := range entries {
+		words[i] = entry.Word
+		if entry.Category == "" && entry.Severity == 0 && entry.Action == "" {
+			continue
+		}
+		metas[entry.Word] = filter.Meta{
+			Category: entry.Category,
+			Severity: entry.Severity,
+			Action:   entry.Action,
+		}
+	}
+	return words, metas, nil
+}
+
+// withMetas 使用附加信息包装过滤器，没有附加信息或过滤器不支持时返回原过滤器
+func withMetas(ft filter.SensitivewordFilter, metas map[string]filter.Meta) filter.SensitivewordFilter {
+	mf, ok := ft.(filter.MatchFilter)
+	if !ok || len(metas) == 0 {
+		return ft
+	}
+	return filter.NewMetaFilter(mf, metas)
 }
 
-func (dm *SensitivewordManager) checkVersion() {
-	time.AfterFunc(dm.interval, func() {
-		storeVersion := dm.sensitivewordStore.Version()
-		if dm.version < storeVersion {
-			dm.filterMux.Lock()
-			switch dm.filter.(type) {
-			case *dfa.NodeFilter:
-				dm.filter = dfa.NewNodeChanFilter(dm.sensitivewordStore.Read())
-			case *newdfa.NodeFilter:
-				dm.filter = newdfa.NewNodeChanFilter(dm.sensitivewordStore.Read())
+// checkVersion 通过 words、excludes 接收存储的变更并重新加载，直到 ctx 取消
+// 存储不支持监听(store.Watchable)或监听中断时，按检查频率检查存储版本
+func (dm *SensitivewordManager) checkVersion(ctx context.Context, words, excludes <-chan uint64) {
+	defer dm.wg.Done()
+	var (
+		ticker *time.Ticker
+		tick   <-chan time.Time
+	)
+	poll := func() {
+		if ticker == nil {
+			ticker = time.NewTicker(dm.interval)
+			tick = ticker.C
+		}
+	}
+	defer func() {
+		if ticker != nil {
+			ticker.Stop()
+		}
+	}()
+	if words == nil || (dm.excludesStore != nil && excludes == nil) {
+		poll()
+	}
+	for {
+		// 首次检查开始监听前发生的变更
+		if err := dm.lockReload(ctx); err != nil {
+			return
+		}
+		_ = dm.reload(false)
+		dm.unlockReload()
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick:
+		case _, ok := <-words:
+			if !ok {
+				words = nil
+				poll()
+			}
+		case _, ok := <-excludes:
+			if !ok {
+				excludes = nil
+				poll()
+			}
+		}
+	}
+}
+
+// watch 监听存储的变更，存储不支持监听或监听失败时返回nil
+func watch(ctx context.Context, s store.SensitivewordStore) <-chan uint64 {
+	w, ok := s.(store.Watchable)
+	if !ok {
+		return nil
+	}
+	ch, err := w.Watch(ctx)
+	if err != nil {
+		return nil
+	}
+	return ch
+}
+
+// lockReload 等待其它重新加载完成，ctx 取消时返回error
+func (dm *SensitivewordManager) lockReload(ctx context.Context) error {
+	select {
+	case dm.reloading <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (dm *SensitivewordManager) unlockReload() {
+	<-dm.reloading
+}
+
+// reload 存储版本变化或 force 为true时重新加载过滤器及白名单
+// 存储提供变更记录且过滤器支持增量更新时，仅应用变更，否则从存储读取全部数据重新创建；
+// 过滤器、白名单及审核策略全部创建成功后才一同替换，任一步骤失败时保留之前的全部状态，并在下次检查时重试；
+// 读取的敏感词为空时默认视为失败，SetAllowEmptyDictionary 开启后应用空的过滤器
+func (dm *SensitivewordManager) reload(force bool) error {
+	storeVersion := dm.sensitivewordStore.Version()
+	reloadWords := force || dm.version < storeVersion
+	var excludesVersion uint64
+	reloadExcludes := false
+	if dm.excludesStore != nil {
+		excludesVersion = dm.excludesStore.Version()
+		reloadExcludes = force || dm.excludesVersion < excludesVersion
+	}
+	if !reloadWords && !reloadExcludes {
+		return nil
+	}
+
+	dm.reloadStart()
+	start := time.Now()
+	stats := ReloadStats{
+		Version:         dm.version,
+		ExcludesVersion: dm.excludesVersion,
+	}
+	var (
+		ft        filter.SensitivewordFilter
+		metas     map[string]filter.Meta
+		whitelist filter.SensitivewordFilter
+	)
+	if reloadWords && !force {
+		if cs, ok := readChanges(dm.sensitivewordStore, dm.version); ok {
+			if ft, ok = cs.apply(dm.filter); ok {
+				metas = cs.applyMetas(dm.metas)
+				reloadWords = false
+				stats.Incremental = true
+				stats.Added += len(cs.added)
+				stats.Removed += len(cs.removed)
+				stats.Version = cs.version
 			}
-			dm.filterMux.Unlock()
+		}
+	}
+	if reloadWords {
+		words, m, err := readDict(dm.sensitivewordStore)
+		dm.filterMux.RLock()
+		allowEmpty := dm.allowEmpty
+		dm.filterMux.RUnlock()
+		if err == nil && len(words) == 0 && !allowEmpty {
+			err = ErrEmptyDictionary
+		}
+		if err != nil {
+			return dm.reloadError(err)
+		}
+		if ft = dm.build(words); ft == nil {
+			// 过滤器实现无法重新创建时不再重试
 			dm.version = storeVersion
+			return dm.reloadError(ErrUnsupportedFilter)
+		}
+		metas = m
+		stats.Words = len(words)
+		stats.Version = storeVersion
+	}
+	if reloadExcludes && !force {
+		if cs, ok := readChanges(dm.excludesStore, dm.excludesVersion); ok {
+			if whitelist, ok = cs.apply(dm.whitelist); ok {
+				reloadExcludes = false
+				stats.Incremental = true
+				stats.Added += len(cs.added)
+				stats.Removed += len(cs.removed)
+				stats.ExcludesVersion = cs.version
+			}
 		}
-		dm.checkVersion()
-	})
+	}
+	if reloadExcludes {
+		allows, err := dm.excludesStore.ReadAll()
+		if err != nil {
+			return dm.reloadError(err)
+		}
+		whitelist = dm.buildWhitelist(allows)
+		stats.Excludes = len(allows)
+		stats.ExcludesVersion = excludesVersion
+	}
+	var policy *Policy
+	if ft != nil {
+		p, err := dm.loadPolicy()
+		if err != nil {
+			return dm.reloadError(err)
+		}
+		policy = p
+	}
+
+	dm.filterMux.Lock()
+	if ft != nil {
+		dm.filter = ft
+		dm.metaFilter = withMetas(ft, metas)
+		if policy != nil {
+			dm.policy = policy
+		}
+	}
+	if whitelist != nil {
+		dm.whitelist = whitelist
+	}
+	dm.filterMux.Unlock()
+	if ft != nil {
+		dm.metas = metas
+		dm.version = stats.Version
+	}
+	if whitelist != nil {
+		dm.excludesVersion = stats.ExcludesVersion
+	}
+	stats.Duration = time.Since(start)
+	dm.reloadSuccess(stats)
+	return nil
+}
+
+// Reload 立即从存储重新加载过滤器、白名单及审核策略
+// 等待其它重新加载完成时 ctx 取消返回 ctx.Err()，当前的过滤器实现无法重新创建时返回 ErrUnsupportedFilter
+func (dm *SensitivewordManager) Reload(ctx context.Context) error {
+	if atomic.LoadInt32(&dm.closed) == 1 {
+		return ErrClosed
+	}
+	if err := dm.lockReload(ctx); err != nil {
+		return err
+	}
+	defer dm.unlockReload()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return dm.reload(true)
+}
+
+// Close 停止检查存储版本，并等待进行中的重新加载完成
+func (dm *SensitivewordManager) Close() error {
+	if !atomic.CompareAndSwapInt32(&dm.closed, 0, 1) {
+		return nil
+	}
+	dm.cancel()
+	dm.wg.Wait()
+	dm.reloading <- struct{}{}
+	dm.unlockReload()
+	return nil
 }
 
 // SensitiveWordStore 获取敏感词存储接口
@@ -72,15 +386,90 @@ func (dm *SensitivewordManager) SensitiveWordStore() store.SensitivewordStore {
 	return dm.sensitivewordStore
 }
 
-// ExcludesStore 获取敏感词存储接口
+// ExcludesStore 获取白名单存储接口
 func (dm *SensitivewordManager) ExcludesStore() store.SensitivewordStore {
 	return dm.excludesStore
 }
 
 // Filter 获取敏感词过滤接口
+// 如果敏感词存储支持附加信息(store.EntryStore)，FindMatches 返回的命中将携带词条的附加信息；
+// 如果提供了白名单存储，完全落在白名单短语内的敏感词将被忽略
 func (dm *SensitivewordManager) Filter() filter.SensitivewordFilter {
 	dm.filterMux.RLock()
-	ft := dm.filter
+	ft, whitelist := dm.metaFilter, dm.whitelist
+	dm.filterMux.RUnlock()
+	mf, ok := ft.(filter.MatchFilter)
+	if !ok {
+		return ft
+	}
+	allow, ok := whitelist.(filter.Matcher)
+	if !ok {
+		return mf
+	}
+	return filter.NewWhitelistFilter(mf, allow)
+}
+
+// SetPolicy 设置审核策略，为nil时使用默认的审核策略
+func (dm *SensitivewordManager) SetPolicy(p *Policy) {
+	if p == nil {
+		p = DefaultPolicy()
+	}
+	dm.filterMux.Lock()
+	dm.policy = p
+	dm.filterMux.Unlock()
+}
+
+// SetPolicyLoader 设置审核策略的加载函数，立即加载审核策略并在敏感词重新加载时一同重新加载
+// 加载失败时返回error，重新加载失败时保留之前的审核策略，加载的审核策略为nil时使用默认的审核策略
+func (dm *SensitivewordManager) SetPolicyLoader(load func() (*Policy, error)) error {
+	p, err := load()
+	if err != nil {
+		return err
+	}
+	if p == nil {
+		p = DefaultPolicy()
+	}
+	dm.filterMux.Lock()
+	dm.policy = p
+	dm.policyLoader = load
+	dm.filterMux.Unlock()
+	return nil
+}
+
+// loadPolicy 使用加载函数加载审核策略，未设置加载函数时返回nil
+func (dm *SensitivewordManager) loadPolicy() (*Policy, error) {
+	dm.filterMux.RLock()
+	load := dm.policyLoader
 	dm.filterMux.RUnlock()
-	return ft
+	if load == nil {
+		return nil, nil
+	}
+	p, err := load()
+	if err != nil {
+		return nil, fmt.Errorf("sensitivewordfilter: reload policy: %v", err)
+	}
+	if p == nil {
+		return DefaultPolicy(), nil
+	}
+	return p, nil
+}
+
+// SetAllowEmptyDictionary 设置重新加载时是否应用空的敏感词库
+// 默认不应用，读取的敏感词为空时返回 ErrEmptyDictionary 并保留之前的过滤器，以免存储异常时清空敏感词
+func (dm *SensitivewordManager) SetAllowEmptyDictionary(allow bool) {
+	dm.filterMux.Lock()
+	dm.allowEmpty = allow
+	dm.filterMux.Unlock()
+}
+
+// Policy 获取当前的审核策略
+func (dm *SensitivewordManager) Policy() *Policy {
+	dm.filterMux.RLock()
+	defer dm.filterMux.RUnlock()
+	return dm.policy
+}
+
+// Decide 使用当前的敏感词及审核策略得出文本的审核结论
+func (dm *SensitivewordManager) Decide(text string) Decision {
+	return dm.Policy().Decide(dm.Filter(), text)
 }
@@ -0,0 +1,408 @@
+package sensitivewordfilter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hellobchain/sensitivewordfilter/filter"
+	"github.com/hellobchain/sensitivewordfilter/filter/dfa"
+	"github.com/hellobchain/sensitivewordfilter/filter/newdfa"
+	"github.com/hellobchain/sensitivewordfilter/filter/rule"
+	"github.com/hellobchain/sensitivewordfilter/store"
+	"github.com/hellobchain/sensitivewordfilter/store/memory"
+)
+
+func TestManagerWhitelist(t *testing.T) {
+	words, err := memory.NewMemoryStore(memory.MemoryConfig{DataSource: []string{"力"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	allows, err := memory.NewMemoryStore(memory.MemoryConfig{DataSource: []string{"力量"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager := NewSensitivewordManager(words, allows, newdfa.NewNodeChanFilter(words.Read()), time.Millisecond*10)
+	defer manager.Close()
+
+	if manager.Filter().IsExist("力量") {
+		t.Errorf("expect 力量 to be allowed")
+	}
+	if !manager.Filter().IsExist("暴力") {
+		t.Errorf("expect 暴力 to be found")
+	}
+
+	if err := allows.Write("暴力"); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for manager.Filter().IsExist("暴力") {
+		if time.Now().After(deadline) {
+			t.Fatalf("whitelist not reloaded after version change")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func TestManagerWhitelistLiteral(t *testing.T) {
+	words, err := memory.NewMemoryStore(memory.MemoryConfig{DataSource: []string{"赌*场"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	allows, err := memory.NewMemoryStore(memory.MemoryConfig{DataSource: []string{"赌*场"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager := NewSensitivewordManager(words, allows, rule.NewNodeChanFilter(words.Read()))
+	defer manager.Close()
+
+	// 白名单短语按字面匹配，不会作为规则编译
+	if manager.Filter().IsExist("赌*场") {
+		t.Errorf("expect literal phrase to be allowed")
+	}
+	if !manager.Filter().IsExist("去赌博的场子") {
+		t.Errorf("expect rule match not allowed by literal phrase")
+	}
+}
+
+func TestManagerWhitelistNormalizerOnly(t *testing.T) {
+	words, err := memory.NewMemoryStore(memory.MemoryConfig{DataSource: []string{"暴力", "cunt"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	allows, err := memory.NewMemoryStore(memory.MemoryConfig{DataSource: []string{"暴力美学", "SCUNTHORPE"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := []filter.Option{filter.WithNormalizer(filter.CaseFold), filter.WithMaxGap(1, nil)}
+	manager := NewSensitivewordManager(words, allows, newdfa.NewNodeChanFilter(words.Read(), opts...))
+	defer manager.Close()
+
+	// 白名单使用相同的归一化，但不使用间隔匹配
+	if manager.Filter().IsExist("暴力美学") || manager.Filter().IsExist("Scunthorpe") {
+		t.Errorf("expect allow phrases matched with the same normalizer")
+	}
+	if !manager.Filter().IsExist("暴-力-美-学") {
+		t.Errorf("expect gapped form not allowed by literal phrase")
+	}
+}
+
+func TestManagerMeta(t *testing.T) {
+	words, err := memory.NewMemoryStore(memory.MemoryConfig{
+		DataSource: []string{"广告"},
+		Entries:    []store.Entry{{Word: "暴力", Category: "abuse", Severity: 4, Action: store.ActionBlock}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager := NewSensitivewordManager(words, nil, newdfa.NewNodeChanFilter(words.Read()), time.Millisecond*10)
+	defer manager.Close()
+
+	matches, err := manager.Filter().(filter.Matcher).FindMatches("暴力广告")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("expect 2 matches, got %+v", matches)
+	}
+	expect := filter.Meta{Category: "abuse", Severity: 4, Action: store.ActionBlock}
+	if matches[0].Meta != expect {
+		t.Errorf("got meta %+v, expect %+v", matches[0].Meta, expect)
+	}
+	if matches[1].Meta != (filter.Meta{}) {
+		t.Errorf("expect no meta for plain word, got %+v", matches[1].Meta)
+	}
+
+	// 通过Write写入已存在的敏感词不会清除附加信息
+	if err := words.Write("暴力"); err != nil {
+		t.Fatal(err)
+	}
+	if err := words.WriteEntries(store.Entry{Word: "广告", Category: "ads", Severity: 2, Action: store.ActionMask}); err != nil {
+		t.Fatal(err)
+	}
+	if err := words.WriteEntries(store.Entry{Word: "x", Severity: 9}); err == nil {
+		t.Errorf("expect invalid severity rejected")
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		matches, _ = manager.Filter().(filter.Matcher).FindMatches("暴力广告")
+		if len(matches) == 2 && matches[1].Meta.Category == "ads" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("meta not reloaded after version change, got %+v", matches)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	if matches[0].Meta != expect {
+		t.Errorf("expect meta kept after Write, got %+v", matches[0].Meta)
+	}
+}
+
+func TestManagerReload(t *testing.T) {
+	words, err := memory.NewMemoryStore(memory.MemoryConfig{DataSource: []string{"暴力"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager := NewSensitivewordManager(words, nil, newdfa.NewNodeChanFilter(words.Read()))
+	defer manager.Close()
+
+	manager.Filter().Add("广告")
+	if !manager.Filter().IsExist("广告") {
+		t.Fatalf("expect added word found")
+	}
+	if err := manager.Reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if manager.Filter().IsExist("广告") {
+		t.Errorf("expect filter rebuilt from store")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := manager.Reload(ctx); err != context.Canceled {
+		t.Errorf("expect context canceled, got %v", err)
+	}
+
+	unknown := NewSensitivewordManager(words, nil, filter.NewMetaFilter(newdfa.NewNodeFilter(nil).(filter.MatchFilter), nil))
+	if err := unknown.Reload(context.Background()); err != ErrUnsupportedFilter {
+		t.Errorf("expect unsupported filter, got %v", err)
+	}
+}
+
+func TestManagerClose(t *testing.T) {
+	words, err := memory.NewMemoryStore(memory.MemoryConfig{DataSource: []string{"暴力"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	manager := NewSensitivewordManagerContext(ctx, words, nil, newdfa.NewNodeChanFilter(words.Read()), time.Millisecond)
+	cancel()
+	time.Sleep(time.Millisecond * 20)
+	if err := words.Write("广告"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond * 20)
+	if manager.Filter().IsExist("广告") {
+		t.Errorf("expect polling stopped after context canceled")
+	}
+
+	if err := manager.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.Close(); err != nil {
+		t.Errorf("expect repeated close to succeed, got %v", err)
+	}
+	if err := manager.Reload(context.Background()); err != ErrClosed {
+		t.Errorf("expect closed, got %v", err)
+	}
+}
+
+// failingStore 读取失败的敏感词存储
+type failingStore struct {
+	*memory.MemoryStore
+	err error
+}
+
+func (fs *failingStore) ReadAll() ([]string, error) {
+	if fs.err != nil {
+		return nil, fs.err
+	}
+	return fs.MemoryStore.ReadAll()
+}
+
+func (fs *failingStore) ReadAllEntries() ([]store.Entry, error) {
+	if fs.err != nil {
+		return nil, fs.err
+	}
+	return fs.MemoryStore.ReadAllEntries()
+}
+
+func TestManagerReloadHooks(t *testing.T) {
+	ms, err := memory.NewMemoryStore(memory.MemoryConfig{DataSource: []string{"暴力"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	words := &failingStore{MemoryStore: ms}
+	manager := NewSensitivewordManager(words, nil, newdfa.NewNodeChanFilter(words.Read()))
+	defer manager.Close()
+
+	var (
+		starts int
+		stats  []ReloadStats
+		errs   []error
+	)
+	manager.OnReloadStart(func() { starts++ })
+	manager.OnReloadSuccess(func(s ReloadStats) { stats = append(stats, s) })
+	manager.OnReloadError(func(err error) { errs = append(errs, err) })
+
+	if err := words.Write("广告"); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.Reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 || stats[0].Words != 2 || stats[0].Version != words.Version() {
+		t.Errorf("unexpected reload stats %+v", stats)
+	}
+
+	readErr := errors.New("read failed")
+	words.err = readErr
+	if err := manager.Reload(context.Background()); err != readErr {
+		t.Errorf("expect read error, got %v", err)
+	}
+	if !manager.Filter().IsExist("广告") {
+		t.Errorf("expect previous filter kept after failed read")
+	}
+
+	words.err = nil
+	if err := words.Remove("暴力", "广告"); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.Reload(context.Background()); err != ErrEmptyDictionary {
+		t.Errorf("expect empty dictionary, got %v", err)
+	}
+	if !manager.Filter().IsExist("暴力") {
+		t.Errorf("expect previous filter kept after empty read")
+	}
+
+	if starts != 3 || len(errs) != 2 || len(stats) != 1 {
+		t.Errorf("got %d starts, %d errors, %d successes", starts, len(errs), len(stats))
+	}
+
+	// 开启后应用清空的敏感词库
+	manager.SetAllowEmptyDictionary(true)
+	if err := manager.Reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if manager.Filter().IsExist("暴力") {
+		t.Errorf("expect empty dictionary applied")
+	}
+}
+
+func TestManagerReloadAtomic(t *testing.T) {
+	words, err := memory.NewMemoryStore(memory.MemoryConfig{DataSource: []string{"暴力"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms, err := memory.NewMemoryStore(memory.MemoryConfig{DataSource: []string{"暴力美学"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	excludes := &failingStore{MemoryStore: ms}
+	manager := NewSensitivewordManager(words, excludes, newdfa.NewNodeChanFilter(words.Read()))
+	defer manager.Close()
+
+	policyErr := errors.New("load failed")
+	var loadErr error
+	if err := manager.SetPolicyLoader(func() (*Policy, error) { return DefaultPolicy(), loadErr }); err != nil {
+		t.Fatal(err)
+	}
+	version := manager.version
+
+	// 审核策略加载失败时不替换过滤器
+	loadErr = policyErr
+	if err := words.Write("广告"); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.Reload(context.Background()); err == nil {
+		t.Errorf("expect policy error")
+	}
+	if manager.Filter().IsExist("广告") || manager.version != version {
+		t.Errorf("expect filter and version kept after failed policy load")
+	}
+
+	// 白名单读取失败时同样不替换过滤器
+	loadErr = nil
+	excludes.err = errors.New("read failed")
+	if err := manager.Reload(context.Background()); err != excludes.err {
+		t.Errorf("expect excludes read error, got %v", err)
+	}
+	if manager.Filter().IsExist("广告") || manager.version != version {
+		t.Errorf("expect filter and version kept after failed excludes read")
+	}
+
+	excludes.err = nil
+	if err := manager.Reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !manager.Filter().IsExist("广告") || manager.version != words.Version() {
+		t.Errorf("expect filter reloaded after errors cleared")
+	}
+}
+
+func TestManagerWatch(t *testing.T) {
+	words, err := memory.NewMemoryStore(memory.MemoryConfig{DataSource: []string{"暴力"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 检查频率很长，只能通过监听变更重新加载
+	manager := NewSensitivewordManager(words, nil, newdfa.NewNodeChanFilter(words.Read()), time.Hour)
+	defer manager.Close()
+
+	if err := words.Write("广告"); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for !manager.Filter().IsExist("广告") {
+		if time.Now().After(deadline) {
+			t.Fatalf("filter not reloaded after store notification")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func TestManagerIncrementalReload(t *testing.T) {
+	words, err := memory.NewMemoryStore(memory.MemoryConfig{DataSource: []string{"暴力", "广告"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager := NewSensitivewordManager(words, nil, dfa.NewNodeChanFilter(words.Read()))
+	defer manager.Close()
+	var stats ReloadStats
+	manager.OnReloadSuccess(func(s ReloadStats) { stats = s })
+
+	old := manager.Filter()
+	if err := words.Write("赌博"); err != nil {
+		t.Fatal(err)
+	}
+	if err := words.Remove("广告"); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.reload(false); err != nil {
+		t.Fatal(err)
+	}
+	if !stats.Incremental || stats.Added != 1 || stats.Removed != 1 || stats.Version != words.Version() {
+		t.Errorf("expect incremental reload, got %+v", stats)
+	}
+	// 写入已存在的敏感词没有变更，不更新版本
+	version := words.Version()
+	if err := words.Write("暴力", "赌博"); err != nil {
+		t.Fatal(err)
+	}
+	if words.Version() != version {
+		t.Errorf("expect version unchanged after writing existing words, got %d, expect %d", words.Version(), version)
+	}
+	ft := manager.Filter()
+	if !ft.IsExist("赌博") || ft.IsExist("广告") || !ft.IsExist("暴力") {
+		t.Errorf("expect changes applied")
+	}
+	if !old.IsExist("广告") || old.IsExist("赌博") {
+		t.Errorf("expect previous filter unchanged")
+	}
+
+	// 变更记录被截断时重新创建过滤器
+	for i := 0; i <= store.DefaultLogSize; i++ {
+		if err := words.Write(fmt.Sprintf("色情%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := manager.reload(false); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Incremental || stats.Words != 3+store.DefaultLogSize {
+		t.Errorf("expect full rebuild after truncated log, got %+v", stats)
+	}
+}
@@ -0,0 +1,80 @@
+package store
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultLogSize 默认保留的变更记录条数
+const DefaultLogSize = 1024
+
+// ErrLogTruncated 所需的变更记录已被截断
+var ErrLogTruncated = errors.New("store: change log truncated")
+
+// Change 一次写入或移除产生的变更
+type Change struct {
+	// Version 变更后的版本号
+	Version uint64
+	// Added 新增或更新的词条
+	Added []Entry
+	// Removed 移除的敏感词
+	Removed []string
+}
+
+// ChangeLog 提供变更记录的存储
+type ChangeLog interface {
+	// Changes 获取版本号 since 之后的所有变更，按版本号排序
+	// 所需的变更记录已被截断时返回 ErrLogTruncated
+	Changes(since uint64) ([]Change, error)
+}
+
+// NewLog 创建最多保留 size 条变更记录的变更日志
+func NewLog(size int) *Log {
+	return &Log{size: size}
+}
+
+// Log 保存最近变更的变更日志，零值保留 DefaultLogSize 条变更记录
+type Log struct {
+	mux     sync.Mutex
+	size    int
+	base    uint64
+	changes []Change
+}
+
+// Commit 将 version 加1并记录变更，返回变更后的版本号
+// 版本号的增加与记录在同一锁内完成，保证变更记录与版本号一致
+func (l *Log) Commit(version *uint64, c Change) uint64 {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	size := l.size
+	if size <= 0 {
+		size = DefaultLogSize
+	}
+	c.Version = atomic.AddUint64(version, 1)
+	if len(l.changes) == 0 {
+		l.base = c.Version - 1
+	}
+	l.changes = append(l.changes, c)
+	if n := len(l.changes) - size; n > 0 {
+		l.base = l.changes[n-1].Version
+		l.changes = append(l.changes[:0:0], l.changes[n:]...)
+	}
+	return c.Version
+}
+
+// Changes 实现ChangeLog接口
+func (l *Log) Changes(since uint64) ([]Change, error) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if since < l.base {
+		return nil, ErrLogTruncated
+	}
+	var result []Change
+	for _, c := range l.changes {
+		if c.Version > since {
+			result = append(result, c)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,34 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLog(t *testing.T) {
+	var version uint64
+	l := NewLog(2)
+	l.Commit(&version, Change{Added: []Entry{{Word: "a"}}})
+	l.Commit(&version, Change{Removed: []string{"a"}})
+	if v := l.Commit(&version, Change{Added: []Entry{{Word: "b"}}}); v != 3 {
+		t.Errorf("expect version 3, got %d", v)
+	}
+
+	changes, err := l.Changes(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []Change{
+		{Version: 2, Removed: []string{"a"}},
+		{Version: 3, Added: []Entry{{Word: "b"}}},
+	}
+	if !reflect.DeepEqual(changes, expect) {
+		t.Errorf("got changes %+v, expect %+v", changes, expect)
+	}
+	if changes, err := l.Changes(3); err != nil || len(changes) != 0 {
+		t.Errorf("expect no changes, got %+v %v", changes, err)
+	}
+	if _, err := l.Changes(0); err != ErrLogTruncated {
+		t.Errorf("expect truncated, got %v", err)
+	}
+}
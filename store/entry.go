@@ -0,0 +1,62 @@
+package store
+
+import "fmt"
+
+const (
+	// ActionBlock 命中后拒绝内容
+	ActionBlock = "block"
+	// ActionReview 命中后转人工审核
+	ActionReview = "review"
+	// ActionMask 命中后屏蔽敏感词
+	ActionMask = "mask"
+)
+
+const (
+	// MinSeverity 最低严重程度
+	MinSeverity = 1
+	// MaxSeverity 最高严重程度
+	MaxSeverity = 5
+)
+
+// Entry 带有分类、严重程度及处理方式的敏感词词条
+type Entry struct {
+	// Word 敏感词
+	Word string `json:"word"`
+	// Category 分类，如 politics、porn、ads、abuse
+	Category string `json:"category,omitempty"`
+	// Severity 严重程度(MinSeverity-MaxSeverity)，0表示未设置
+	Severity int `json:"severity,omitempty"`
+	// Action 命中后的处理方式(ActionBlock、ActionReview、ActionMask)，空字符串表示未设置
+	Action string `json:"action,omitempty"`
+}
+
+// Validate 检查词条是否有效
+func (e Entry) Validate() error {
+	if e.Word == "" {
+		return fmt.Errorf("store: 敏感词为空")
+	}
+	if e.Severity != 0 && (e.Severity < MinSeverity || e.Severity > MaxSeverity) {
+		return fmt.Errorf("store: %q 的严重程度 %d 超出范围", e.Word, e.Severity)
+	}
+	switch e.Action {
+	case "", ActionBlock, ActionReview, ActionMask:
+	default:
+		return fmt.Errorf("store: %q 的处理方式 %q 无效", e.Word, e.Action)
+	}
+	return nil
+}
+
+// EntryStore 提供带附加信息的敏感词词条的读取、写入存储接口
+// 通过 Write 写入的敏感词不会清除已有的附加信息
+type EntryStore interface {
+	SensitivewordStore
+
+	// WriteEntries 将词条写入存储区，已存在的词条将被覆盖，如果写入失败则返回error
+	WriteEntries(entries ...Entry) error
+
+	// ReadEntries 以迭代的方式读取词条
+	ReadEntries() <-chan Entry
+
+	// ReadAllEntries 获取所有的词条，如果获取失败则返回error
+	ReadAllEntries() ([]Entry, error)
+}
@@ -1,10 +1,14 @@
 package leveldb
 
 import (
+	"context"
+	"encoding/json"
 	"sync/atomic"
 
 	"github.com/antlinker/go-cmap"
 	"github.com/syndtr/goleveldb/leveldb"
+
+	"github.com/hellobchain/sensitivewordfilter/store"
 )
 
 const (
@@ -13,7 +17,7 @@ const (
 
 // NewLevelDbStore 创建敏感词内存存储
 func NewLevelDbStore(config LevelDbConfig) (*LevelDbStore, error) {
-	store := &LevelDbStore{
+	dbStore := &LevelDbStore{
 		dataStore: cmap.NewConcurrencyMap(),
 	}
 
@@ -23,21 +27,35 @@ func NewLevelDbStore(config LevelDbConfig) (*LevelDbStore, error) {
 	}
 
 	if config.Path != "" {
-		store.Db, err = leveldb.OpenFile(config.Path, nil)
+		dbStore.Db, err = leveldb.OpenFile(config.Path, nil)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	iter := store.Db.NewIterator(nil, nil)
+	iter := dbStore.Db.NewIterator(nil, nil)
 	for iter.Next() {
-		err := store.dataStore.Set(string(iter.Key()), 1)
+		entry := store.Entry{Word: string(iter.Key())}
+		// 值为空时为通过Write写入的敏感词，否则为JSON编码的附加信息
+		if value := iter.Value(); len(value) > 0 {
+			if err := json.Unmarshal(value, &entry); err != nil {
+				iter.Release()
+				return nil, err
+			}
+			entry.Word = string(iter.Key())
+		}
+		err := dbStore.dataStore.Set(entry.Word, entry)
 		if err != nil {
+			iter.Release()
 			return nil, err
 		}
 	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
 
-	return store, nil
+	return dbStore, nil
 }
 
 // LevelDbConfig 敏感词内存存储配置
@@ -49,6 +67,8 @@ type LevelDbConfig struct {
 type LevelDbStore struct {
 	version   uint64
 	dataStore cmap.ConcurrencyMap
+	notifier  store.Notifier
+	log       store.Log
 	Db        *leveldb.DB
 }
 
@@ -57,17 +77,56 @@ func (ms *LevelDbStore) Write(words ...string) error {
 	if len(words) == 0 {
 		return nil
 	}
+	var added []store.Entry
 	for i, l := 0, len(words); i < l; i++ {
+		// 已存在的敏感词保留其附加信息
+		if ok, _ := ms.dataStore.Contains(words[i]); ok {
+			continue
+		}
 		err := ms.Db.Put([]byte(words[i]), nil, nil)
 		if err != nil {
 			return err
 		}
-		err = ms.dataStore.Set(words[i], 1)
+		entry := store.Entry{Word: words[i]}
+		err = ms.dataStore.Set(words[i], entry)
+		if err != nil {
+			return err
+		}
+		added = append(added, entry)
+	}
+	// 敏感词均已存在时没有变更，不记录变更也不更新版本
+	if len(added) == 0 {
+		return nil
+	}
+	ms.notifier.Notify(ms.log.Commit(&ms.version, store.Change{Added: added}))
+	return nil
+}
+
+// WriteEntries WriteEntries
+func (ms *LevelDbStore) WriteEntries(entries ...store.Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	for _, entry := range entries {
+		if err := entry.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, entry := range entries {
+		value, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		err = ms.Db.Put([]byte(entry.Word), value, nil)
+		if err != nil {
+			return err
+		}
+		err = ms.dataStore.Set(entry.Word, entry)
 		if err != nil {
 			return err
 		}
 	}
-	atomic.AddUint64(&ms.version, 1)
+	ms.notifier.Notify(ms.log.Commit(&ms.version, store.Change{Added: append([]store.Entry(nil), entries...)}))
 	return nil
 }
 
@@ -83,6 +142,28 @@ func (ms *LevelDbStore) Read() <-chan string {
 	return chResult
 }
 
+// ReadEntries ReadEntries
+func (ms *LevelDbStore) ReadEntries() <-chan store.Entry {
+	chResult := make(chan store.Entry)
+	go func() {
+		for ele := range ms.dataStore.Elements() {
+			chResult <- ele.Value.(store.Entry)
+		}
+		close(chResult)
+	}()
+	return chResult
+}
+
+// ReadAllEntries ReadAllEntries
+func (ms *LevelDbStore) ReadAllEntries() ([]store.Entry, error) {
+	values := ms.dataStore.Values()
+	result := make([]store.Entry, len(values))
+	for i, v := range values {
+		result[i] = v.(store.Entry)
+	}
+	return result, nil
+}
+
 // ReadAll ReadAll
 func (ms *LevelDbStore) ReadAll() ([]string, error) {
 	dataKeys := ms.dataStore.Keys()
@@ -109,11 +190,21 @@ func (ms *LevelDbStore) Remove(words ...string) error {
 			return err
 		}
 	}
-	atomic.AddUint64(&ms.version, 1)
+	ms.notifier.Notify(ms.log.Commit(&ms.version, store.Change{Removed: append([]string(nil), words...)}))
 	return nil
 }
 
 // Version Version
 func (ms *LevelDbStore) Version() uint64 {
-	return ms.version
+	return atomic.LoadUint64(&ms.version)
+}
+
+// Watch 监听敏感词的变更
+func (ms *LevelDbStore) Watch(ctx context.Context) (<-chan uint64, error) {
+	return ms.notifier.Watch(ctx)
+}
+
+// Changes 获取版本号 since 之后的变更，仅保留最近 store.DefaultLogSize 次变更
+func (ms *LevelDbStore) Changes(since uint64) ([]store.Change, error) {
+	return ms.log.Changes(since)
 }
@@ -2,10 +2,13 @@ package memory
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"sync/atomic"
 
 	"github.com/antlinker/go-cmap"
+
+	"github.com/hellobchain/sensitivewordfilter/store"
 )
 
 const (
@@ -23,7 +26,7 @@ func NewMemoryStore(config MemoryConfig) (*MemoryStore, error) {
 	}
 	if dataLen := len(config.DataSource); dataLen > 0 {
 		for i := 0; i < dataLen; i++ {
-			err := memStore.dataStore.Set(config.DataSource[i], 1)
+			err := memStore.dataStore.Set(config.DataSource[i], store.Entry{Word: config.DataSource[i]})
 			if err != nil {
 				return nil, err
 			}
@@ -40,13 +43,21 @@ func NewMemoryStore(config MemoryConfig) (*MemoryStore, error) {
 				}
 				return nil, err
 			}
-			err = memStore.dataStore.Set(line, 1)
+			err = memStore.dataStore.Set(line, store.Entry{Word: line})
 			if err != nil {
 				return nil, err
 			}
 		}
 		buf.Reset()
 	}
+	for _, entry := range config.Entries {
+		if err := entry.Validate(); err != nil {
+			return nil, err
+		}
+		if err := memStore.dataStore.Set(entry.Word, entry); err != nil {
+			return nil, err
+		}
+	}
 	return memStore, nil
 }
 
@@ -58,12 +69,16 @@ type MemoryConfig struct {
 	Delim byte
 	// DataSource 敏感词数据源
 	DataSource []string
+	// Entries 带附加信息的敏感词数据源
+	Entries []store.Entry
 }
 
 // MemoryStore 提供内存存储敏感词
 type MemoryStore struct {
 	version   uint64
 	dataStore cmap.ConcurrencyMap
+	notifier  store.Notifier
+	log       store.Log
 }
 
 // Write Write
@@ -71,13 +86,41 @@ func (ms *MemoryStore) Write(words ...string) error {
 	if len(words) == 0 {
 		return nil
 	}
+	var added []store.Entry
 	for i, l := 0, len(words); i < l; i++ {
-		err := ms.dataStore.Set(words[i], 1)
+		// 已存在的敏感词保留其附加信息
+		if ok, _ := ms.dataStore.Contains(words[i]); ok {
+			continue
+		}
+		entry := store.Entry{Word: words[i]}
+		ms.dataStore.SetIfAbsent(words[i], entry)
+		added = append(added, entry)
+	}
+	// 敏感词均已存在时没有变更，不记录变更也不更新版本
+	if len(added) == 0 {
+		return nil
+	}
+	ms.notifier.Notify(ms.log.Commit(&ms.version, store.Change{Added: added}))
+	return nil
+}
+
+// WriteEntries WriteEntries
+func (ms *MemoryStore) WriteEntries(entries ...store.Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	for _, entry := range entries {
+		if err := entry.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, entry := range entries {
+		err := ms.dataStore.Set(entry.Word, entry)
 		if err != nil {
 			return err
 		}
 	}
-	atomic.AddUint64(&ms.version, 1)
+	ms.notifier.Notify(ms.log.Commit(&ms.version, store.Change{Added: append([]store.Entry(nil), entries...)}))
 	return nil
 }
 
@@ -93,6 +136,28 @@ func (ms *MemoryStore) Read() <-chan string {
 	return chResult
 }
 
+// ReadEntries ReadEntries
+func (ms *MemoryStore) ReadEntries() <-chan store.Entry {
+	chResult := make(chan store.Entry)
+	go func() {
+		for ele := range ms.dataStore.Elements() {
+			chResult <- ele.Value.(store.Entry)
+		}
+		close(chResult)
+	}()
+	return chResult
+}
+
+// ReadAllEntries ReadAllEntries
+func (ms *MemoryStore) ReadAllEntries() ([]store.Entry, error) {
+	values := ms.dataStore.Values()
+	result := make([]store.Entry, len(values))
+	for i, v := range values {
+		result[i] = v.(store.Entry)
+	}
+	return result, nil
+}
+
 // ReadAll ReadAll
 func (ms *MemoryStore) ReadAll() ([]string, error) {
 	dataKeys := ms.dataStore.Keys()
@@ -115,11 +180,21 @@ func (ms *MemoryStore) Remove(words ...string) error {
 			return err
 		}
 	}
-	atomic.AddUint64(&ms.version, 1)
+	ms.notifier.Notify(ms.log.Commit(&ms.version, store.Change{Removed: append([]string(nil), words...)}))
 	return nil
 }
 
 // Version Version
 func (ms *MemoryStore) Version() uint64 {
-	return ms.version
+	return atomic.LoadUint64(&ms.version)
+}
+
+// Watch 监听敏感词的变更
+func (ms *MemoryStore) Watch(ctx context.Context) (<-chan uint64, error) {
+	return ms.notifier.Watch(ctx)
+}
+
+// Changes 获取版本号 since 之后的变更，仅保留最近 store.DefaultLogSize 次变更
+func (ms *MemoryStore) Changes(since uint64) ([]store.Change, error) {
+	return ms.log.Changes(since)
 }
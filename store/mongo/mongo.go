@@ -1,19 +1,25 @@
 package mongo
 
 import (
+	"context"
 	"errors"
 	"log"
 	"os"
 	"sync/atomic"
+	"time"
 
 	"github.com/globalsign/mgo/bson"
 
 	"github.com/globalsign/mgo"
+
+	"github.com/hellobchain/sensitivewordfilter/store"
 )
 
 const (
 	// DefaultCollection 默认存储敏感词的集合
 	DefaultCollection = "dirties"
+	// DefaultWatchInterval 监听变更时检查是否停止监听的频率
+	DefaultWatchInterval = time.Second
 )
 
 // NewMongoStore 创建敏感词MongoDB存储
@@ -53,15 +59,28 @@ type MongoConfig struct {
 }
 
 type _Sensitiveword struct {
-	Value string `bson:"Value"`
+	Value    string `bson:"Value"`
+	Category string `bson:"Category,omitempty"`
+	Severity int    `bson:"Severity,omitempty"`
+	Action   string `bson:"Action,omitempty"`
+}
+
+func (sw _Sensitiveword) entry() store.Entry {
+	return store.Entry{
+		Word:     sw.Value,
+		Category: sw.Category,
+		Severity: sw.Severity,
+		Action:   sw.Action,
+	}
 }
 
 // MongoStore 提供内存存储敏感词
 type MongoStore struct {
-	version uint64
-	session *mgo.Session
-	config  MongoConfig
-	lg      *log.Logger
+	version  uint64
+	watchers int32
+	session  *mgo.Session
+	config   MongoConfig
+	lg       *log.Logger
 }
 
 func (ms *MongoStore) c(h func(*mgo.Collection)) {
@@ -80,17 +99,95 @@ func (ms *MongoStore) Write(words ...string) error {
 	var err error
 	ms.c(func(c *mgo.Collection) {
 		for i, l := 0, len(words); i < l; i++ {
-			_, err = c.Upsert(_Sensitiveword{Value: words[i]}, _Sensitiveword{Value: words[i]})
+			// 仅设置Value，已存在的敏感词保留其附加信息
+			_, err = c.Upsert(bson.M{"Value": words[i]}, bson.M{"$set": bson.M{"Value": words[i]}})
 		}
 	})
 	if err != nil {
 		return err
 	}
 
-	atomic.AddUint64(&ms.version, 1)
+	ms.bump()
 	return nil
 }
 
+// WriteEntries WriteEntries
+func (ms *MongoStore) WriteEntries(entries ...store.Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	for _, entry := range entries {
+		if err := entry.Validate(); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	ms.c(func(c *mgo.Collection) {
+		for _, entry := range entries {
+			doc := _Sensitiveword{
+				Value:    entry.Word,
+				Category: entry.Category,
+				Severity: entry.Severity,
+				Action:   entry.Action,
+			}
+			if _, err = c.Upsert(bson.M{"Value": entry.Word}, doc); err != nil {
+				return
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	ms.bump()
+	return nil
+}
+
+// ReadEntries ReadEntries
+func (ms *MongoStore) ReadEntries() <-chan store.Entry {
+	chResult := make(chan store.Entry)
+	go func() {
+		ms.c(func(c *mgo.Collection) {
+			iter := c.Find(nil).Select(bson.M{"_id": 0}).Sort("Value").Iter()
+			var sensitiveword _Sensitiveword
+			for iter.Next(&sensitiveword) {
+				chResult <- sensitiveword.entry()
+				sensitiveword = _Sensitiveword{}
+			}
+			if err := iter.Close(); err != nil {
+				ms.lg.Println(err)
+			}
+			close(chResult)
+		})
+	}()
+	return chResult
+}
+
+// ReadAllEntries ReadAllEntries
+func (ms *MongoStore) ReadAllEntries() ([]store.Entry, error) {
+	var (
+		item   _Sensitiveword
+		result []store.Entry
+		err    error
+	)
+
+	ms.c(func(c *mgo.Collection) {
+		iter := c.Find(nil).Select(bson.M{"_id": 0}).Sort("Value").Iter()
+		for iter.Next(&item) {
+			result = append(result, item.entry())
+			item = _Sensitiveword{}
+		}
+		err = iter.Close()
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
 // Read Read
 func (ms *MongoStore) Read() <-chan string {
 	chResult := make(chan string)
@@ -124,7 +221,7 @@ func (ms *MongoStore) ReadAll() ([]string, error) {
 		for iter.Next(&item) {
 			result = append(result, item.Value)
 		}
-		err = iter.Err()
+		err = iter.Close()
 	})
 
 	if err != nil {
@@ -148,11 +245,81 @@ func (ms *MongoStore) Remove(words ...string) error {
 		return err
 	}
 
-	atomic.AddUint64(&ms.version, 1)
+	ms.bump()
 	return nil
 }
 
+// bump 本地写入后增加版本号，存在监听时由变更流推进版本号，避免同一变更重复增加
+func (ms *MongoStore) bump() {
+	if atomic.LoadInt32(&ms.watchers) == 0 {
+		atomic.AddUint64(&ms.version, 1)
+	}
+}
+
 // Version Version
 func (ms *MongoStore) Version() uint64 {
-	return ms.version
+	return atomic.LoadUint64(&ms.version)
+}
+
+// Watch 使用变更流(change streams)监听集合的变更，包括其它进程写入的变更
+// 变更流要求MongoDB以副本集或分片集群方式部署，单机部署时返回error
+// 版本号推进到变更的集群时间，多个监听收到同一变更时版本号只增加一次
+func (ms *MongoStore) Watch(ctx context.Context) (<-chan uint64, error) {
+	sess := ms.session.Clone()
+	c := sess.DB(ms.config.DB).C(ms.config.Collection)
+	stream, err := c.Watch(nil, mgo.ChangeStreamOptions{MaxAwaitTimeMS: DefaultWatchInterval})
+	if err != nil {
+		sess.Close()
+		return nil, err
+	}
+	ch := make(chan uint64, 1)
+	atomic.AddInt32(&ms.watchers, 1)
+	go func() {
+		defer atomic.AddInt32(&ms.watchers, -1)
+		defer close(ch)
+		defer sess.Close()
+		defer stream.Close()
+		var event _ChangeEvent
+		for ctx.Err() == nil {
+			if !stream.Next(&event) {
+				if err := stream.Err(); err != nil {
+					ms.lg.Println(err)
+					return
+				}
+				// 等待超时，继续监听
+				continue
+			}
+			version := ms.advance(uint64(event.ClusterTime))
+			event = _ChangeEvent{}
+			select {
+			case ch <- version:
+			default:
+				select {
+				case <-ch:
+				default:
+				}
+				ch <- version
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// _ChangeEvent 变更流事件，仅读取变更的集群时间
+type _ChangeEvent struct {
+	ClusterTime bson.MongoTimestamp `bson:"clusterTime"`
+}
+
+// advance 将版本号推进到变更的集群时间 ts，已不小于 ts 时保持不变，返回当前的版本号
+// 事件不包含集群时间(MongoDB 4.0以前)时版本号加1
+func (ms *MongoStore) advance(ts uint64) uint64 {
+	if ts == 0 {
+		return atomic.AddUint64(&ms.version, 1)
+	}
+	for {
+		version := atomic.LoadUint64(&ms.version)
+		if version >= ts || atomic.CompareAndSwapUint64(&ms.version, version, ts) {
+			return atomic.LoadUint64(&ms.version)
+		}
+	}
 }
@@ -58,5 +58,5 @@ func (ms *RedisStore) Remove(words ...string) error {
 
 // Version Version
 func (ms *RedisStore) Version() uint64 {
-	return ms.version
+	return atomic.LoadUint64(&ms.version)
 }
@@ -0,0 +1,59 @@
+package store
+
+import (
+	"context"
+	"sync"
+)
+
+// Watchable 支持推送变更通知的存储
+type Watchable interface {
+	// Watch 返回变更通知通道，存储发生变更时发送变更后的版本号
+	// 接收不及时的通知将被合并为最新的版本号，ctx 取消或无法继续监听时关闭通道
+	Watch(ctx context.Context) (<-chan uint64, error)
+}
+
+// Notifier 向所有监听者广播存储的变更，零值可直接使用
+type Notifier struct {
+	mux      sync.Mutex
+	watchers map[chan uint64]struct{}
+}
+
+// Watch 增加监听者，ctx 取消时移除监听者并关闭通道
+func (n *Notifier) Watch(ctx context.Context) (<-chan uint64, error) {
+	ch := make(chan uint64, 1)
+	n.mux.Lock()
+	if n.watchers == nil {
+		n.watchers = make(map[chan uint64]struct{})
+	}
+	n.watchers[ch] = struct{}{}
+	n.mux.Unlock()
+	go func() {
+		<-ctx.Done()
+		n.mux.Lock()
+		delete(n.watchers, ch)
+		close(ch)
+		n.mux.Unlock()
+	}()
+	return ch, nil
+}
+
+// Notify 通知所有监听者存储已变更为 version
+func (n *Notifier) Notify(version uint64) {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+	for ch := range n.watchers {
+		select {
+		case ch <- version:
+		default:
+			// 丢弃未接收的旧版本号
+			select {
+			case <-ch:
+			default:
+			}
+			select {
+			case ch <- version:
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNotifier(t *testing.T) {
+	var n Notifier
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := n.Watch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.Notify(1)
+	n.Notify(2)
+	if v := <-ch; v != 2 {
+		t.Errorf("expect notifications merged to latest version, got %d", v)
+	}
+	cancel()
+	if _, ok := <-ch; ok {
+		t.Errorf("expect channel closed after context canceled")
+	}
+	n.Notify(3)
+}